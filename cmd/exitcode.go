@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes reported to scripts and CI
+const (
+	exitSuccess     = 0   // All objects migrated (or verified)
+	exitFatal       = 1   // Configuration, connection or other fatal error
+	exitPartial     = 2   // Run completed, but some objects failed
+	exitInterrupted = 130 // Stopped by SIGINT/SIGTERM
+)
+
+// exitError carries the process exit code for a failed run
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode wraps err so that main exits with code
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code for the error returned by a command
+func exitCode(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+	return exitFatal
+}
+
+// partialFailure reports failed objects of an otherwise completed run
+func partialFailure(failed int64, what string) error {
+	return withExitCode(exitPartial, fmt.Errorf("%d objects failed to %s", failed, what))
+}
+
+// exitStatus names the outcome of a run for the --summary-json line
+func exitStatus(code int) string {
+	switch code {
+	case exitSuccess:
+		return "success"
+	case exitPartial:
+		return "partial"
+	case exitInterrupted:
+		return "interrupted"
+	}
+	return "fatal"
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"minio2rustfs/internal/app"
+)
+
+// runSummary is the --summary-json line. The counts are left out when the run
+// failed before the migrator was created.
+type runSummary struct {
+	Status   string `json:"status"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	*app.Summary
+}
+
+// printSummary writes the run summary as a single JSON line to stdout. Logs go
+// to stderr and the progress display has stopped by now, so it is always the
+// last line of stdout.
+func printSummary(migrator *app.Migrator, err error) {
+	code := exitCode(err)
+	summary := runSummary{Status: exitStatus(code), ExitCode: code}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+	if migrator != nil {
+		s := migrator.Summary()
+		summary.Summary = &s
+	}
+
+	line, jsonErr := json.Marshal(summary)
+	if jsonErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode summary: %v\n", jsonErr)
+		return
+	}
+	fmt.Println(string(line))
+}
@@ -0,0 +1,9 @@
+// Package minio2rustfs embeds repository files that the command needs at runtime
+package minio2rustfs
+
+import _ "embed"
+
+// ConfigTemplate is the commented example configuration written by init-config
+//
+//go:embed config.yaml.example
+var ConfigTemplate []byte
@@ -2,13 +2,16 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
 	"minio2rustfs/internal/checkpoint"
 	"minio2rustfs/internal/config"
 	"minio2rustfs/internal/metrics"
+	"minio2rustfs/internal/notify"
 	"minio2rustfs/internal/progress"
 	"minio2rustfs/internal/storage"
 	"minio2rustfs/internal/worker"
@@ -16,6 +19,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// progressSaveInterval is how often progress is persisted to the checkpoint
+const progressSaveInterval = 10 * time.Second
+
 // Migrator represents the main migration application
 type Migrator struct {
 	cfg        *config.Config
@@ -25,48 +31,199 @@ type Migrator struct {
 	checkpoint checkpoint.Store
 	metrics    *metrics.Collector
 	workers    *worker.Pool
+	spillDir   string
+	remote     *checkpoint.RemoteSync
+	jobs       []migrationJob
+	ranges     []config.RangeEntry // Byte ranges to migrate instead of listing the source
+	objectKeys []string            // Objects to migrate instead of listing the source
+	failed     *failedManifest     // Keys of failed objects for replay; nil without --failed-manifest
+	metadata   *metadataTransform  // Edits user metadata of tasks; nil without --metadata-rules
+	inventory  *inventoryWriter    // Source inventory written while listing; nil without --inventory
+	runID      string              // Identifies this run in webhook events
+	webhook    *notify.Webhook     // nil when no webhook URL is configured
+	counts     sync.WaitGroup      // Background re-counts, waited for when Run returns
 }
 
 // New creates a new migrator instance
 func New(cfg *config.Config, logger *zap.Logger) (*Migrator, error) {
-	// Create source client
-	srcClient, err := storage.NewMinIOClient(storage.Config{
-		Endpoint:  cfg.Source.Endpoint,
-		AccessKey: cfg.Source.AccessKey,
-		SecretKey: cfg.Source.SecretKey,
-		Secure:    cfg.Source.Secure,
-	})
+	logConfigWarnings(cfg, logger)
+
+	srcClient, dstClient, err := newClients(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create source client: %w", err)
+		return nil, err
 	}
 
-	// Create destination client
-	dstClient, err := storage.NewMinIOClient(storage.Config{
-		Endpoint:  cfg.Target.Endpoint,
-		AccessKey: cfg.Target.AccessKey,
-		SecretKey: cfg.Target.SecretKey,
-		Secure:    cfg.Target.Secure,
-	})
+	jobs, err := newJobs(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create destination client: %w", err)
+		return nil, err
+	}
+
+	if cfg.Migration.ReadOrder == config.ReadOrderLayout {
+		if _, ok := srcClient.(storage.LayoutLister); !ok {
+			logger.Warn("Source does not expose its storage layout, reading in key order instead (--read-order=layout is experimental)")
+		}
+	}
+
+	// The buffer memory check depends on the concurrency, so it is chosen first
+	if cfg.Migration.AutoConcurrency {
+		cfg.Migration.Concurrency = autoConcurrency(context.Background(), cfg, srcClient, jobs, logger)
+	}
+	if err := checkBufferMemory(cfg, logger); err != nil {
+		return nil, err
+	}
+	if cfg.Migration.LowMemory {
+		applyMemoryLimit(logger)
+	}
+
+	var ranges []config.RangeEntry
+	if cfg.Migration.RangeManifest != "" {
+		ranges, err = config.ParseRangeManifest(cfg.Migration.RangeManifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objectKeys []string
+	if cfg.Migration.ObjectList != "" {
+		objectKeys, err = config.ParseObjectList(cfg.Migration.ObjectList)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var priorities map[string]int
+	if cfg.Migration.PriorityManifest != "" {
+		priorities, err = config.ParsePriorityManifest(cfg.Migration.PriorityManifest)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Restore or claim the remote checkpoint before the local database is opened
+	var remote *checkpoint.RemoteSync
+	if cfg.Migration.RemoteCheckpoint != "" {
+		remote, err = newRemoteSync(cfg, dstClient, logger)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Create checkpoint store
-	checkpointStore, err := checkpoint.NewSQLiteStore(cfg.Migration.Checkpoint)
+	checkpointStore, err := newCheckpointStore(cfg, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+		return nil, err
+	}
+
+	// Create a per-run spill directory so leftovers can be removed wholesale on shutdown
+	var spillDir string
+	if cfg.Migration.SpillDir != "" {
+		spillDir, err = os.MkdirTemp(cfg.Migration.SpillDir, "minio2rustfs-spill-")
+		if err != nil {
+			checkpointStore.Close()
+			return nil, fmt.Errorf("failed to create spill directory: %w", err)
+		}
+	}
+
+	inventory, err := newInventoryWriter(cfg.Migration.Inventory)
+	if err != nil {
+		checkpointStore.Close()
+		if spillDir != "" {
+			os.RemoveAll(spillDir)
+		}
+		return nil, err
 	}
 
+	// Already validated by config.Load
+	contentTypes, _ := config.ParseContentTypeMap(cfg.Migration.ContentTypeMap)
+	metadataRules, _ := config.ParseMetadataRules(cfg.Migration.MetadataRules)
+	maxBandwidth, _ := config.ParseSize(cfg.Migration.MaxBandwidth)
+	skipCompare, _ := config.ParseSkipCompare(cfg.Migration.SkipCompare)
+
 	// Create metrics collector
 	metricsCollector := metrics.New()
 
+	runID := time.Now().UTC().Format("20060102T150405Z")
+	var webhook *notify.Webhook
+	if cfg.Migration.WebhookURL != "" {
+		webhook = notify.NewWebhook(cfg.Migration.WebhookURL, logger.With(zap.String("component", "webhook")))
+	}
+	notifyFailures := webhook != nil && cfg.Migration.WebhookOnFailure
+	failed := newFailedManifest(cfg.Migration.FailedManifest)
+
+	var onFailure func(worker.Task, error)
+	if notifyFailures || failed != nil {
+		onFailure = func(task worker.Task, err error) {
+			failed.add(task)
+			if notifyFailures {
+				webhook.NotifyFailure(notify.FailureEvent{
+					RunID:  runID,
+					Bucket: task.Bucket,
+					Key:    task.CheckpointKey(),
+					Error:  err.Error(),
+				})
+			}
+		}
+	}
+
 	// Create worker pool
 	workerPool := worker.NewPool(cfg.Migration.Concurrency, worker.Config{
-		MultipartThreshold: cfg.Migration.MultipartThreshold,
-		PartSize:           cfg.Migration.PartSize,
-		Retries:            cfg.Migration.Retries,
-		RetryBackoffMs:     cfg.Migration.RetryBackoffMs,
-		SkipExisting:       cfg.Migration.SkipExisting,
+		MultipartThreshold:  cfg.Migration.MultipartThreshold,
+		MultipartMinSize:    cfg.Migration.MultipartMinSize,
+		NoMultipart:         cfg.Migration.NoMultipart,
+		PartSize:            cfg.Migration.PartSize,
+		PartConcurrency:     cfg.Migration.PartConcurrency,
+		ContentTypes:        contentTypes,
+		Retries:             cfg.Migration.Retries,
+		ChecksumRetries:     cfg.Migration.ChecksumRetries,
+		VerifyAfterPut:      cfg.Migration.VerifyAfterPut,
+		VerifyKeys:          cfg.Migration.VerifyKeys,
+		VerifyContent:       cfg.Migration.VerifyContent,
+		RetryBackoffMs:      cfg.Migration.RetryBackoffMs,
+		DeferredRetries:     cfg.Migration.DeferredRetries,
+		RetryCooldown:       cfg.Migration.RetryCooldown,
+		AutoThrottle:        cfg.Migration.AutoThrottle,
+		ThrottleMaxDelay:    cfg.Migration.ThrottleMaxDelay,
+		SkipExisting:        cfg.Migration.SkipExisting,
+		CompareETag:         skipCompare.ETag,
+		CompareSize:         skipCompare.Size,
+		CompareMetadata:     skipCompare.Metadata,
+		SyncMetadata:        cfg.Migration.SyncMetadata,
+		VerifyCompleted:     cfg.Migration.VerifyCompletedOnResume,
+		CopyACL:             cfg.Migration.CopyACL,
+		CopyTags:            cfg.Migration.CopyTags,
+		PreserveMtime:       cfg.Migration.PreserveMtime,
+		Conditional:         cfg.Migration.Conditional,
+		TagFailedSource:     cfg.Migration.TagFailedSource,
+		MaxTaskAttempts:     cfg.Migration.MaxTaskAttempts,
+		SkipUnreadable:      cfg.Migration.SkipUnreadable,
+		HeadConcurrency:     cfg.Migration.HeadConcurrency,
+		MaxSourceReads:      cfg.Migration.MaxSourceReads,
+		MaxObjectsPerSecond: cfg.Migration.MaxObjectsPerSecond,
+		MaxBandwidth:        maxBandwidth,
+		Priorities:          priorities,
+		Resume:              cfg.Migration.Resume,
+		RecheckSource:       cfg.Migration.RecheckSource,
+		SpillDir:            spillDir,
+		SpillThreshold:      cfg.Migration.SpillThreshold,
+		StreamParts:         cfg.Migration.StreamParts,
+		QueueDepth:          cfg.Migration.TaskQueueDepth(),
+		PoolBuffers:         cfg.Migration.LowMemory,
+		CopyIfNewer:         cfg.Migration.CopyIfNewer,
+		MtimeSkewTolerance:  cfg.Migration.MtimeSkewTolerance,
+		SlowThreshold:       cfg.Migration.SlowThreshold,
+		IdleTimeout:         cfg.Migration.IdleTimeout,
+		AttemptTimeout:      cfg.Migration.AttemptTimeout,
+		ObjectTimeout:       cfg.Migration.ObjectTimeout,
+		SkipExpiringWithin:  cfg.Migration.SkipExpiringWithin,
+		Watch:               cfg.Migration.Watch,
+		VerboseProgress:     cfg.Migration.VerboseProgress,
+		SmallBatchSize:      cfg.Migration.SmallBatchSize,
+		SmallBatchThreshold: cfg.Migration.SmallBatchThreshold,
+		PackSmall:           cfg.Migration.PackSmall,
+		PackThreshold:       cfg.Migration.PackThreshold,
+		PackMaxSize:         cfg.Migration.PackMaxSize,
+		PackPrefix:          cfg.Migration.PackPrefix,
+		OnFailure:           onFailure,
 	}, srcClient, dstClient, checkpointStore, metricsCollector, logger)
 
 	return &Migrator{
@@ -77,19 +234,198 @@ func New(cfg *config.Config, logger *zap.Logger) (*Migrator, error) {
 		checkpoint: checkpointStore,
 		metrics:    metricsCollector,
 		workers:    workerPool,
+		spillDir:   spillDir,
+		remote:     remote,
+		jobs:       jobs,
+		ranges:     ranges,
+		objectKeys: objectKeys,
+		failed:     failed,
+		metadata:   newMetadataTransform(metadataRules, logger),
+		inventory:  inventory,
+		runID:      runID,
+		webhook:    webhook,
 	}, nil
 }
 
+// logConfigWarnings logs the settings adjusted while loading cfg
+func logConfigWarnings(cfg *config.Config, logger *zap.Logger) {
+	for _, warning := range cfg.Warnings {
+		logger.Warn("Adjusted configuration: " + warning)
+	}
+}
+
+// newRemoteSync downloads the remote checkpoint on resume. A fresh run refuses
+// to start when a remote checkpoint already exists. The checkpoint is kept in the destination bucket of the first job.
+func newRemoteSync(cfg *config.Config, dstClient storage.Client, logger *zap.Logger) (*checkpoint.RemoteSync, error) {
+	remote, err := checkpoint.NewRemoteSync(dstClient, cfg.Migration.Jobs[0].DstBucket, cfg.Migration.RemoteCheckpoint,
+		cfg.Migration.Checkpoint, logger.With(zap.String("component", "remote-checkpoint")))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if !cfg.Migration.Resume {
+		if err := remote.Claim(ctx); errors.Is(err, checkpoint.ErrRemoteExists) {
+			return nil, fmt.Errorf("%w; continue it with --resume, or remove it if no run is using it", err)
+		} else if err != nil {
+			return nil, err
+		}
+		return remote, nil
+	}
+
+	found, err := remote.Download(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore remote checkpoint: %w", err)
+	}
+	if found {
+		logger.Info("Restored checkpoint from destination bucket",
+			zap.String("key", cfg.Migration.RemoteCheckpoint),
+		)
+	} else {
+		logger.Info("No remote checkpoint found, starting fresh",
+			zap.String("key", cfg.Migration.RemoteCheckpoint),
+		)
+	}
+	return remote, nil
+}
+
+// newCheckpointStore opens the checkpoint database: PostgreSQL for a
+// postgres:// URL, otherwise a SQLite file. A run without resume does not
+// depend on earlier records, so when the database cannot be opened (e.g. a
+// read-only or full filesystem) it continues without a checkpoint instead of
+// aborting. Resuming is impossible without the database and fails.
+func newCheckpointStore(cfg *config.Config, logger *zap.Logger) (checkpoint.Store, error) {
+	store, err := openCheckpointStore(cfg)
+	if err == nil {
+		return store, nil
+	}
+	if cfg.Migration.Resume {
+		return nil, fmt.Errorf("failed to create checkpoint store: %w", err)
+	}
+
+	logger.Warn("Cannot open checkpoint database, continuing without a checkpoint; this run cannot be resumed",
+		zap.String("checkpoint", checkpoint.DisplayLocation(cfg.Migration.Checkpoint)),
+		zap.Error(err),
+	)
+	return checkpoint.NewNoopStore(), nil
+}
+
+// openCheckpointStore opens the checkpoint backend selected by the checkpoint
+// location
+func openCheckpointStore(cfg *config.Config) (checkpoint.Store, error) {
+	if checkpoint.IsPostgresDSN(cfg.Migration.Checkpoint) {
+		return checkpoint.NewPostgresStore(cfg.Migration.Checkpoint)
+	}
+	return checkpoint.NewSQLiteStore(cfg.Migration.Checkpoint, checkpointOptions(cfg))
+}
+
+// checkpointOptions resolves the SQLite tuning from the preset, with explicitly
+// configured values taking precedence
+func checkpointOptions(cfg *config.Config) checkpoint.SQLiteOptions {
+	var opts checkpoint.SQLiteOptions
+	if cfg.Migration.CheckpointPreset == config.CheckpointPresetLarge {
+		opts = checkpoint.LargeMigrationOptions
+	}
+	if cfg.Migration.CheckpointPageSize != 0 {
+		opts.PageSize = cfg.Migration.CheckpointPageSize
+	}
+	if cfg.Migration.CheckpointMmapSize != 0 {
+		opts.MmapSize = cfg.Migration.CheckpointMmapSize
+	}
+	if cfg.Migration.CheckpointCacheSize != 0 {
+		opts.CacheSize = cfg.Migration.CheckpointCacheSize
+	}
+	if cfg.Migration.LowMemory {
+		// Each connection keeps its own page cache
+		opts.MaxOpenConns = 1
+	}
+	return opts
+}
+
+// newClients creates the source and destination storage clients
+func newClients(cfg *config.Config) (storage.Client, storage.Client, error) {
+	// Create source client
+	srcClient, err := storage.NewMinIOClient(storage.Config{
+		Endpoint:  cfg.Source.Endpoint,
+		AccessKey: cfg.Source.AccessKey,
+		SecretKey: cfg.Source.SecretKey,
+		Secure:    cfg.Source.Secure,
+
+		CredentialsFile: cfg.Source.CredentialsFile,
+
+		ClientCert: cfg.Source.ClientCert,
+		ClientKey:  cfg.Source.ClientKey,
+		CACert:     cfg.Source.CACert,
+
+		Proxy: cfg.Source.Proxy,
+
+		ListRateLimit: cfg.Migration.ListRateLimit,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create source client: %w", err)
+	}
+
+	// Create destination client
+	dstConfig := storage.Config{
+		Endpoint:  cfg.Target.Endpoint,
+		AccessKey: cfg.Target.AccessKey,
+		SecretKey: cfg.Target.SecretKey,
+		Secure:    cfg.Target.Secure,
+
+		CredentialsFile: cfg.Target.CredentialsFile,
+
+		ClientCert: cfg.Target.ClientCert,
+		ClientKey:  cfg.Target.ClientKey,
+		CACert:     cfg.Target.CACert,
+
+		Proxy: cfg.Target.Proxy,
+	}
+	var dstClient storage.Client
+	switch cfg.Target.Type {
+	case config.StorageTypeHTTPSink:
+		dstClient, err = storage.NewHTTPSinkClient(dstConfig)
+	default:
+		dstClient, err = storage.NewMinIOClient(dstConfig)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create destination client: %w", err)
+	}
+
+	return srcClient, dstClient, nil
+}
+
 // Run executes the migration process
 func (m *Migrator) Run(ctx context.Context) error {
 	m.logger.Info("Starting migration",
-		zap.String("bucket", m.cfg.Migration.Bucket),
-		zap.String("prefix", m.cfg.Migration.Prefix),
+		zap.String("run_id", m.runID),
+		zap.String("src_endpoint", m.cfg.Source.Endpoint),
+		zap.String("dst_endpoint", m.cfg.Target.Endpoint),
+		zap.Bool("reverse", m.cfg.Migration.Reverse),
+		zap.Any("jobs", m.cfg.Migration.Jobs),
 		zap.String("object", m.cfg.Migration.Object),
 		zap.Int("concurrency", m.cfg.Migration.Concurrency),
 		zap.Bool("dry_run", m.cfg.Migration.DryRun),
+		zap.Bool("resume", m.cfg.Migration.Resume),
+		zap.Bool("watch", m.cfg.Migration.Watch),
+		zap.Bool("listen", m.cfg.Migration.Listen),
 	)
 
+	// Background work of the run, such as a re-count of the source, is
+	// stopped when Run returns, before the checkpoint is closed
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		m.counts.Wait()
+	}()
+
+	// Without a saved listing position the checkpoint only knows the objects
+	// that were reached before the interruption, so resuming lists the source
+	// again and skips the completed objects one by one
+	if m.cfg.Migration.Resume && !m.cfg.Migration.ResumableListing && m.listsPrefix() {
+		m.logger.Info("Resuming from checkpoint: the source is listed again and completed objects are skipped; " +
+			"use --resumable-listing to continue the listing where it stopped instead")
+	}
+
 	// Start metrics server in a goroutine with error handling
 	go func() {
 		if err := m.metrics.StartServer(":8080"); err != nil {
@@ -97,14 +433,137 @@ func (m *Migrator) Run(ctx context.Context) error {
 		}
 	}()
 
+	if m.remote != nil {
+		if store, ok := m.checkpoint.(checkpoint.Snapshotter); ok {
+			go m.remote.Run(ctx, m.cfg.Migration.RemoteCheckpointInterval, store)
+		}
+	}
+
+	if m.cfg.Migration.DetectCaseConflicts && m.listsPrefix() {
+		if err := m.checkCaseConflicts(ctx); err != nil {
+			return err
+		}
+	}
+
+	// In watch mode each pass after the first only picks up objects modified
+	// since the previous pass started (widened by the mtime skew tolerance).
+	var since time.Time
+	for {
+		passStart := time.Now()
+		if err := m.runPass(ctx, since); err != nil {
+			return err
+		}
+
+		since = passStart.Add(-m.cfg.Migration.MtimeSkewTolerance)
+		if m.cfg.Migration.Listen {
+			return m.listen(ctx, since)
+		}
+
+		if !m.cfg.Migration.Watch {
+			break
+		}
+
+		m.logger.Info("Migration pass completed, waiting for next pass",
+			zap.Duration("watch_interval", m.cfg.Migration.WatchInterval),
+		)
+
+		select {
+		case <-time.After(m.cfg.Migration.WatchInterval):
+		case <-ctx.Done():
+			m.logger.Info("Watch mode stopped")
+			return nil
+		}
+	}
+
+	status := m.metrics.GetProgressTracker().GetStatus()
+	m.logger.Info("Migration completed",
+		zap.String("transferred_size", progress.FormatBytes(status.TransferredBytes)),
+		zap.String("skipped_size", progress.FormatBytes(status.SkippedBytes)),
+		zap.String("failed_size", progress.FormatBytes(status.FailedBytes)),
+		zap.Int64("transferred_bytes", status.TransferredBytes),
+		zap.Int64("skipped_bytes", status.SkippedBytes),
+		zap.Int64("failed_bytes", status.FailedBytes),
+		zap.Int64("unreadable_objects", status.Unreadable),
+	)
+	m.notifyCompletion(ctx)
+	return nil
+}
+
+// notifyCompletion posts the run summary to the webhook, if configured. A
+// webhook that cannot be reached is logged but does not fail the run.
+func (m *Migrator) notifyCompletion(ctx context.Context) {
+	if m.webhook == nil {
+		return
+	}
+
+	status := m.metrics.GetProgressTracker().GetStatus()
+	err := m.webhook.NotifyCompletion(ctx, notify.CompletionEvent{
+		RunID:            m.runID,
+		Success:          status.SuccessObjects,
+		Skipped:          status.SkippedObjects,
+		Failed:           status.FailedObjects,
+		Unreadable:       status.Unreadable,
+		TransferredBytes: status.TransferredBytes,
+		SkippedBytes:     status.SkippedBytes,
+		FailedBytes:      status.FailedBytes,
+		DurationSeconds:  time.Since(status.StartTime).Seconds(),
+	})
+	if err != nil {
+		m.logger.Warn("Failed to post completion webhook", zap.Error(err))
+	}
+}
+
+// checkCaseConflicts scans the source for keys that differ only by case and
+// aborts before anything is copied, since a case-insensitive destination
+// would silently overwrite one with the other.
+func (m *Migrator) checkCaseConflicts(ctx context.Context) error {
+	m.logger.Info("Checking for case-conflicting keys...")
+
+	lister := &ObjectLister{
+		client:   m.srcClient,
+		logger:   m.logger,
+		listOpts: storage.ListOptions{NonRecursive: m.cfg.Migration.NonRecursive},
+	}
+
+	var conflicts []CaseConflict
+	for _, job := range m.jobs {
+		found, err := lister.FindCaseConflicts(ctx, job.Bucket, job.Prefix)
+		if err != nil {
+			return fmt.Errorf("failed to check case conflicts: %w", err)
+		}
+		conflicts = append(conflicts, found...)
+	}
+
+	if len(conflicts) == 0 {
+		m.logger.Info("No case-conflicting keys found")
+		return nil
+	}
+
+	for _, conflict := range conflicts {
+		m.logger.Warn("Keys differ only by case", zap.Strings("keys", conflict.Keys))
+	}
+	return fmt.Errorf("found %d groups of keys that differ only by case; resolve them or rerun without --detect-case-conflicts", len(conflicts))
+}
+
+// runPass lists the source and migrates objects modified after since.
+// A zero since means a full pass, which also drives the progress display.
+func (m *Migrator) runPass(ctx context.Context, since time.Time) error {
+	fullPass := since.IsZero()
+
 	// Create task channel
-	tasks := make(chan worker.Task, m.cfg.Migration.Concurrency*2)
+	tasks := make(chan worker.Task, m.cfg.Migration.TaskQueueDepth())
 
 	// Create progress display if enabled and supported and not in dry-run mode
 	var progressDisplay *progress.Display
-	if m.cfg.Migration.ShowProgress && !m.cfg.Migration.DryRun && progress.IsTerminalSupported() {
+	if !fullPass {
+		m.logger.Info("Starting incremental pass", zap.Time("modified_since", since))
+	} else if m.cfg.Migration.ShowProgress && !m.cfg.Migration.DryRun && progress.IsTerminalSupported() {
 		progressTracker := m.metrics.GetProgressTracker()
 		progressDisplay = progress.NewDisplay(progressTracker, 2*time.Second) // 增加更新间隔
+		progressDisplay.SetShowWorkers(m.cfg.Migration.VerboseProgress)
+		if m.cfg.Migration.ProgressTheme == config.ProgressThemeASCII {
+			progressDisplay.SetTheme(progress.ThemeASCII)
+		}
 		m.logger.Info("Progress display enabled")
 	} else {
 		if m.cfg.Migration.DryRun {
@@ -120,51 +579,452 @@ func (m *Migrator) Run(ctx context.Context) error {
 	var wg sync.WaitGroup
 	m.workers.Start(ctx, tasks, &wg)
 
-	// List and enqueue objects
-	lister := &ObjectLister{
-		client: m.srcClient,
-		logger: m.logger,
-	}
-
 	// First pass: count objects and total size for progress tracking
+	counted := false
+	var totalBytes int64
 	if progressDisplay != nil {
-		m.logger.Info("Counting objects for progress tracking...")
-		totalObjects, totalBytes, err := lister.CountObjects(ctx, m.cfg.Migration.Bucket, m.cfg.Migration.Prefix, m.cfg.Migration.Object)
+		// The display starts in listing mode and is stopped after workers complete
+		m.metrics.StartListing(progress.StageCounting)
+		progressDisplay.Start()
+
+		totalObjects, bytes, err := m.countObjects(ctx)
 		if err != nil {
 			m.logger.Warn("Failed to count objects, progress tracking may be inaccurate", zap.Error(err))
 		} else {
+			counted, totalBytes = true, bytes
 			m.metrics.SetTotalCounts(totalObjects, totalBytes)
 			m.logger.Info("Object counting completed",
 				zap.Int64("total_objects", totalObjects),
 				zap.String("total_size", progress.FormatBytes(totalBytes)),
 			)
-			// Start progress display
-			progressDisplay.Start()
-			// Note: We'll stop it after workers complete
+			if m.cfg.Migration.Resume && m.persistsProgress() {
+				m.restoreProgress()
+			}
 		}
 	}
 
-	if err := lister.ListAndEnqueue(ctx, m.cfg.Migration.Bucket, m.cfg.Migration.Prefix, m.cfg.Migration.Object, tasks, m.cfg.Migration.DryRun); err != nil {
-		close(tasks)
-		return fmt.Errorf("failed to list objects: %w", err)
+	if fullPass && m.cfg.Migration.CheckCapacity {
+		if !counted {
+			// Without the progress display the source has not been counted yet
+			m.metrics.StartListing(progress.StageCounting)
+			_, bytes, err := m.countObjects(ctx)
+			m.metrics.FinishListing()
+			if err != nil {
+				m.logger.Warn("Failed to count objects, skipping capacity check", zap.Error(err))
+			} else {
+				counted, totalBytes = true, bytes
+			}
+		}
+		if counted {
+			if err := m.checkCapacity(ctx, totalBytes); err != nil {
+				close(tasks)
+				wg.Wait()
+				if progressDisplay != nil {
+					progressDisplay.Stop()
+				}
+				return err
+			}
+		}
+	}
+
+	// Persist progress periodically so a resumed run can continue from it
+	persistProgress := progressDisplay != nil && m.persistsProgress()
+	persistDone := make(chan struct{})
+	if persistProgress {
+		go m.persistProgress(persistDone)
 	}
 
+	m.metrics.StartListing(progress.StageDispatching)
+	err := m.enqueueJobs(ctx, since, tasks)
+	m.metrics.FinishListing()
+	if flushErr := m.inventory.flush(); flushErr != nil {
+		m.logger.Error("Failed to write inventory", zap.Error(flushErr))
+	}
 	close(tasks)
+	if err != nil {
+		close(persistDone)
+		// Let in-flight tasks record their outcome before the checkpoint is closed
+		m.waitForWorkers(&wg)
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+
 	wg.Wait()
+	m.workers.Flush(ctx)
+	close(persistDone)
 
 	// Stop progress display if it was started
 	if progressDisplay != nil {
+		if persistProgress {
+			m.saveProgress()
+		}
 		progressDisplay.Stop()
 	}
 
-	m.logger.Info("Migration completed")
 	return nil
 }
 
-// Close cleans up resources
+// newLister creates the lister for job. Objects it finds are reported as
+// listing progress, so a long listing phase does not look like a hang.
+func (m *Migrator) newLister(job migrationJob, since time.Time) *ObjectLister {
+	lister := &ObjectLister{
+		client:        m.srcClient,
+		logger:        m.logger,
+		modifiedSince: since,
+		countWorkers:  m.cfg.Migration.CountConcurrency,
+		listOpts:      storage.ListOptions{NonRecursive: m.cfg.Migration.NonRecursive},
+		keys:          job.keys,
+		metadata:      m.metadata,
+		inventory:     m.inventory,
+		ranges:        m.ranges,
+		objectKeys:    m.objectKeys,
+		allowOddKeys:  m.cfg.Migration.AllowWeirdKeys,
+		layoutOrder:   m.cfg.Migration.ReadOrder == config.ReadOrderLayout,
+		onListed:      m.metrics.AddDiscovered,
+	}
+	if m.cfg.Migration.ListOnlyChanged {
+		lister.compareClient = m.dstClient
+		lister.onUnchanged = func(obj storage.ObjectInfo) {
+			m.metrics.IncSkippedWithBytes(obj.Size)
+		}
+	}
+	if m.cfg.Migration.ResumableListing {
+		lister.listState = m.checkpoint
+		lister.resumeListing = m.cfg.Migration.Resume
+	}
+	return lister
+}
+
+// enqueueJobs lists every job, feeding all tasks to the shared pool. Up to
+// ListConcurrency jobs are listed at once; the first failing job stops the
+// others.
+func (m *Migrator) enqueueJobs(ctx context.Context, since time.Time, tasks chan<- worker.Task) error {
+	if m.cfg.Migration.ListConcurrency <= 1 || len(m.jobs) == 1 {
+		for _, job := range m.jobs {
+			if err := m.enqueueJob(ctx, job, since, tasks); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	slots := make(chan struct{}, m.cfg.Migration.ListConcurrency)
+	for _, job := range m.jobs {
+		select {
+		case slots <- struct{}{}:
+		case <-listCtx.Done():
+		}
+		if listCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(job migrationJob) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := m.enqueueJob(listCtx, job, since, tasks); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(job)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// enqueueJob lists one job
+func (m *Migrator) enqueueJob(ctx context.Context, job migrationJob, since time.Time, tasks chan<- worker.Task) error {
+	if len(m.jobs) > 1 {
+		m.logger.Info("Listing job",
+			zap.String("bucket", job.Bucket),
+			zap.String("prefix", job.Prefix),
+			zap.String("dst_bucket", job.DstBucket),
+			zap.String("dst_prefix", job.DstPrefix),
+		)
+	}
+
+	lister := m.newLister(job, since)
+	if err := lister.ListAndEnqueue(ctx, job.Bucket, job.Prefix, m.cfg.Migration.Object, tasks, m.cfg.Migration.DryRun); err != nil {
+		if len(m.jobs) > 1 {
+			return fmt.Errorf("job %s/%s: %w", job.Bucket, job.Prefix, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// listsPrefix reports whether the run migrates everything under the
+// configured prefix, rather than a single object or manifest ranges. Cached
+// totals are only kept for prefix runs.
+func (m *Migrator) listsPrefix() bool {
+	return m.cfg.Migration.Object == "" && m.ranges == nil && m.objectKeys == nil
+}
+
+// persistsProgress reports whether progress is persisted and restored. It is
+// recorded per bucket/prefix, so only single-job prefix runs keep it.
+func (m *Migrator) persistsProgress() bool {
+	return m.listsPrefix() && len(m.jobs) == 1
+}
+
+// countObjects returns the totals of all jobs used to seed progress tracking
+func (m *Migrator) countObjects(ctx context.Context) (int64, int64, error) {
+	var totalObjects, totalBytes int64
+	for _, job := range m.jobs {
+		objects, bytes, err := m.countJob(ctx, job)
+		if err != nil {
+			return 0, 0, err
+		}
+		totalObjects += objects
+		totalBytes += bytes
+	}
+	return totalObjects, totalBytes, nil
+}
+
+// countJob returns the totals of a single job. On resume the totals cached by
+// a previous run are reused instead of re-scanning the source.
+func (m *Migrator) countJob(ctx context.Context, job migrationJob) (int64, int64, error) {
+	lister := m.newLister(job, time.Time{})
+	cacheable := m.listsPrefix()
+
+	if cacheable && m.cfg.Migration.Resume {
+		cached, err := m.checkpoint.GetScanTotals(job.Bucket, job.Prefix)
+		if err != nil {
+			m.logger.Warn("Failed to read cached object totals", zap.Error(err))
+		} else if cached != nil {
+			m.logger.Info("Using cached object totals from checkpoint",
+				zap.String("bucket", job.Bucket),
+				zap.String("prefix", job.Prefix),
+				zap.Time("counted_at", cached.UpdatedAt),
+			)
+			// A background count replaces the totals, so it needs a single job
+			if m.cfg.Migration.RefreshCount && len(m.jobs) == 1 {
+				// The background count must not show up as listing progress
+				lister.onListed = nil
+				m.counts.Add(1)
+				go func() {
+					defer m.counts.Done()
+					m.refreshCount(ctx, lister, job)
+				}()
+			}
+			return cached.Objects, cached.Bytes, nil
+		}
+	}
+
+	m.logger.Info("Counting objects for progress tracking...",
+		zap.String("bucket", job.Bucket),
+		zap.String("prefix", job.Prefix),
+	)
+	totalObjects, totalBytes, err := lister.CountObjects(ctx, job.Bucket, job.Prefix, m.cfg.Migration.Object)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if cacheable {
+		m.saveScanTotals(job, totalObjects, totalBytes)
+	}
+
+	return totalObjects, totalBytes, nil
+}
+
+// refreshCount re-counts the source in the background and updates the progress totals
+func (m *Migrator) refreshCount(ctx context.Context, lister *ObjectLister, job migrationJob) {
+	totalObjects, totalBytes, err := lister.CountObjects(ctx, job.Bucket, job.Prefix, "")
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Warn("Background object count failed", zap.Error(err))
+		}
+		return
+	}
+
+	m.metrics.SetTotalCounts(totalObjects, totalBytes)
+	m.saveScanTotals(job, totalObjects, totalBytes)
+	m.logger.Info("Background object count completed",
+		zap.Int64("total_objects", totalObjects),
+		zap.String("total_size", progress.FormatBytes(totalBytes)),
+	)
+}
+
+func (m *Migrator) saveScanTotals(job migrationJob, totalObjects, totalBytes int64) {
+	err := m.checkpoint.SaveScanTotals(&checkpoint.ScanTotals{
+		Bucket:  job.Bucket,
+		Prefix:  job.Prefix,
+		Objects: totalObjects,
+		Bytes:   totalBytes,
+	})
+	if err != nil {
+		m.logger.Warn("Failed to cache object totals", zap.Error(err))
+	}
+}
+
+// restoreProgress seeds the progress tracker with the progress persisted by a
+// previous run. Failed objects are left out since they are retried.
+func (m *Migrator) restoreProgress() {
+	job := m.jobs[0]
+	state, err := m.checkpoint.GetProgress(job.Bucket, job.Prefix)
+	if err != nil {
+		m.logger.Warn("Failed to read persisted progress", zap.Error(err))
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	m.metrics.GetProgressTracker().Restore(state.SuccessObjects, state.SkippedObjects, state.ProcessedBytes,
+		state.TransferredBytes, state.SkippedBytes, state.Elapsed)
+	m.logger.Info("Restored progress from checkpoint",
+		zap.Int64("processed_objects", state.SuccessObjects+state.SkippedObjects),
+		zap.String("processed_size", progress.FormatBytes(state.ProcessedBytes)),
+		zap.Duration("elapsed", state.Elapsed),
+	)
+}
+
+// persistProgress saves progress every progressSaveInterval until done is closed
+func (m *Migrator) persistProgress(done <-chan struct{}) {
+	ticker := time.NewTicker(progressSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.saveProgress()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (m *Migrator) saveProgress() {
+	job := m.jobs[0]
+	status := m.metrics.GetProgressTracker().GetStatus()
+	err := m.checkpoint.SaveProgress(&checkpoint.ProgressState{
+		Bucket:           job.Bucket,
+		Prefix:           job.Prefix,
+		ProcessedObjects: status.ProcessedObjects,
+		ProcessedBytes:   status.ProcessedBytes,
+		TransferredBytes: status.TransferredBytes,
+		SkippedBytes:     status.SkippedBytes,
+		SuccessObjects:   status.SuccessObjects,
+		SkippedObjects:   status.SkippedObjects,
+		Elapsed:          time.Since(status.StartTime),
+	})
+	if err != nil {
+		m.logger.Warn("Failed to persist progress", zap.Error(err))
+	}
+}
+
+// Summary is the outcome of a run in machine-readable form
+type Summary struct {
+	RunID            string  `json:"run_id"`
+	TotalObjects     int64   `json:"total_objects"`
+	SuccessObjects   int64   `json:"success_objects"`
+	SkippedObjects   int64   `json:"skipped_objects"`
+	FailedObjects    int64   `json:"failed_objects"`
+	LockedObjects    int64   `json:"locked_objects"`
+	Unreadable       int64   `json:"unreadable_objects"`
+	ExpiringObjects  int64   `json:"expiring_objects"`
+	MetadataObjects  int64   `json:"metadata_updated_objects"`
+	TotalBytes       int64   `json:"total_bytes"`
+	TransferredBytes int64   `json:"transferred_bytes"`
+	SkippedBytes     int64   `json:"skipped_bytes"`
+	FailedBytes      int64   `json:"failed_bytes"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+}
+
+// Summary returns the counts of the run so far
+func (m *Migrator) Summary() Summary {
+	status := m.metrics.GetProgressTracker().GetStatus()
+	return Summary{
+		RunID:            m.runID,
+		TotalObjects:     status.TotalObjects,
+		SuccessObjects:   status.SuccessObjects,
+		SkippedObjects:   status.SkippedObjects,
+		FailedObjects:    status.FailedObjects,
+		LockedObjects:    status.LockedObjects,
+		Unreadable:       status.Unreadable,
+		ExpiringObjects:  status.ExpiringObjects,
+		MetadataObjects:  status.MetadataObjects,
+		TotalBytes:       status.TotalBytes,
+		TransferredBytes: status.TransferredBytes,
+		SkippedBytes:     status.SkippedBytes,
+		FailedBytes:      status.FailedBytes,
+		DurationSeconds:  time.Since(status.StartTime).Seconds(),
+	}
+}
+
+// FailedObjects returns the number of objects that failed to migrate
+func (m *Migrator) FailedObjects() int64 {
+	return m.metrics.GetProgressTracker().GetStatus().FailedObjects
+}
+
+// waitForWorkers waits for the workers to finish their current tasks after
+// cancellation, giving up after ShutdownTimeout so that shutdown stays within
+// a container's termination grace period
+func (m *Migrator) waitForWorkers(wg *sync.WaitGroup) {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(m.cfg.Migration.ShutdownTimeout):
+		m.logger.Warn("Timed out waiting for in-flight tasks; they will be retried on resume",
+			zap.Duration("shutdown_timeout", m.cfg.Migration.ShutdownTimeout),
+		)
+	}
+}
+
+// Close cleans up resources. It does not depend on the run context, so it is
+// safe to call after the migration was cancelled.
 func (m *Migrator) Close() error {
+	if m.metrics != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Migration.ShutdownTimeout)
+		if err := m.metrics.Shutdown(ctx); err != nil {
+			m.logger.Warn("Failed to shut down metrics server", zap.Error(err))
+		}
+		cancel()
+	}
+	if m.webhook != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Migration.ShutdownTimeout)
+		m.webhook.Close(ctx)
+		cancel()
+	}
+	if m.remote != nil && m.checkpoint != nil {
+		if store, ok := m.checkpoint.(checkpoint.Snapshotter); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Migration.ShutdownTimeout)
+			if err := m.remote.Upload(ctx, store); err != nil {
+				m.logger.Error("Failed to upload final remote checkpoint", zap.Error(err))
+			}
+			cancel()
+		}
+	}
 	if m.checkpoint != nil {
-		m.checkpoint.Close()
+		if err := m.checkpoint.Close(); err != nil {
+			m.logger.Error("Failed to close checkpoint", zap.Error(err))
+		}
+	}
+	if err := m.inventory.Close(); err != nil {
+		m.logger.Error("Failed to close inventory", zap.Error(err))
+	}
+	if err := m.failed.write(m.logger); err != nil {
+		m.logger.Error("Failed to write replay manifest", zap.Error(err))
+	}
+	if m.spillDir != "" {
+		if err := os.RemoveAll(m.spillDir); err != nil {
+			return fmt.Errorf("failed to remove spill directory: %w", err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/metrics"
+	"minio2rustfs/internal/storage"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// testMetrics is shared by all tests, since a collector registers its metrics
+// globally and can only be created once per process
+var testMetrics = metrics.New()
+
+// newTestMigrator returns a migrator listing jobs from src into store
+func newTestMigrator(cfg *config.Config, src storage.Client, store checkpoint.Store, jobs ...config.JobSpec) *Migrator {
+	m := &Migrator{
+		cfg:        cfg,
+		logger:     zap.NewNop(),
+		srcClient:  src,
+		checkpoint: store,
+		metrics:    testMetrics,
+	}
+	for _, job := range jobs {
+		m.jobs = append(m.jobs, migrationJob{JobSpec: job})
+	}
+	return m
+}
+
+// TestResumeConcurrentJobListing lists two jobs concurrently with resumable
+// listing, kills the run part way through both, and checks that the resumed
+// run continues each job from its own saved position without skipping or
+// repeating an object
+func TestResumeConcurrentJobListing(t *testing.T) {
+	const perJob = 2500 // Three pages of the memory client
+	src := storage.NewMemoryClient("photos", "logs")
+	jobs := []config.JobSpec{
+		{Bucket: "photos", DstBucket: "photos"},
+		{Bucket: "logs", Prefix: "app/", DstBucket: "logs"},
+	}
+	all := make(map[string]bool)
+	for i := 0; i < perJob; i++ {
+		for _, key := range []string{"photos/" + fmt.Sprintf("%05d.jpg", i), "logs/" + fmt.Sprintf("app/%05d.log", i)} {
+			bucket, object, _ := strings.Cut(key, "/")
+			if _, err := src.PutObject(context.Background(), bucket, object, bytes.NewReader([]byte("x")), 1, storage.PutOptions{}); err != nil {
+				t.Fatalf("put %s: %v", key, err)
+			}
+			all[key] = true
+		}
+	}
+	// Outside the logs job's prefix
+	src.PutObject(context.Background(), "logs", "other/ignored.log", bytes.NewReader([]byte("x")), 1, storage.PutOptions{})
+
+	store, err := checkpoint.NewSQLiteStore(filepath.Join(t.TempDir(), "checkpoint.db"), checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("open checkpoint: %v", err)
+	}
+	defer store.Close()
+
+	cfg := &config.Config{}
+	cfg.Migration.ListConcurrency = 2
+	cfg.Migration.ResumableListing = true
+
+	// First run: the first objects handed out complete, then the run is killed
+	const completedBeforeKill = 1800
+	completed := make(map[string]bool)
+	ctx, cancel := context.WithCancel(context.Background())
+	tasks := make(chan worker.Task)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for task := range tasks {
+			if len(completed) == completedBeforeKill {
+				cancel()
+				continue
+			}
+			completed[task.Bucket+"/"+task.Key] = true
+			record := &checkpoint.TaskRecord{Bucket: task.Bucket, Key: task.Key, Size: task.Size, ETag: task.ETag, Status: checkpoint.StatusCompleted}
+			if err := store.SaveTask(record); err != nil {
+				t.Errorf("save %s: %v", task.Key, err)
+			}
+		}
+	}()
+	if err := newTestMigrator(cfg, src, store, jobs...).enqueueJobs(ctx, time.Time{}, tasks); err == nil {
+		t.Fatalf("killed listing returned no error")
+	}
+	close(tasks)
+	<-done
+	cancel()
+
+	perBucket := make(map[string]int)
+	for key := range completed {
+		bucket, _, _ := strings.Cut(key, "/")
+		perBucket[bucket]++
+	}
+	if perBucket["photos"] == 0 || perBucket["logs"] == 0 || len(completed) == len(all) {
+		t.Fatalf("completed %v before the kill, want both jobs interrupted part way", perBucket)
+	}
+
+	// Resumed run
+	cfg.Migration.Resume = true
+	resumed := make(map[string]int)
+	tasks = make(chan worker.Task)
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for task := range tasks {
+			resumed[task.Bucket+"/"+task.Key]++
+		}
+	}()
+	if err := newTestMigrator(cfg, src, store, jobs...).enqueueJobs(context.Background(), time.Time{}, tasks); err != nil {
+		t.Fatalf("resumed listing: %v", err)
+	}
+	close(tasks)
+	<-done
+
+	for key, n := range resumed {
+		if n > 1 {
+			t.Errorf("%s enqueued %d times on resume", key, n)
+		}
+		if completed[key] {
+			t.Errorf("%s completed before the kill but enqueued again", key)
+		}
+		if !all[key] {
+			t.Errorf("%s enqueued but outside the jobs", key)
+		}
+	}
+	for key := range all {
+		if !completed[key] && resumed[key] == 0 {
+			t.Errorf("%s skipped: neither completed before the kill nor enqueued on resume", key)
+		}
+	}
+}
+
+// stallingClient blocks every listing until its context is cancelled, like a
+// source count that outlasts the migration
+type stallingClient struct {
+	*storage.MemoryClient
+	started chan struct{}
+}
+
+func (c *stallingClient) ListObjects(ctx context.Context, bucket, prefix string, opts storage.ListOptions) (<-chan storage.ObjectInfo, <-chan error) {
+	objCh := make(chan storage.ObjectInfo)
+	errCh := make(chan error, 1)
+	close(c.started)
+	go func() {
+		defer close(objCh)
+		<-ctx.Done()
+		errCh <- ctx.Err()
+	}()
+	return objCh, errCh
+}
+
+// TestRefreshCountStopsWithRun checks that a background re-count still
+// listing the source is cancelled and waited for once the run is over, so
+// that it does not write to a checkpoint that is being closed
+func TestRefreshCountStopsWithRun(t *testing.T) {
+	store, err := checkpoint.NewSQLiteStore(filepath.Join(t.TempDir(), "checkpoint.db"), checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("open checkpoint: %v", err)
+	}
+	defer store.Close()
+	job := config.JobSpec{Bucket: testBucket, DstBucket: testBucket}
+	if err := store.SaveScanTotals(&checkpoint.ScanTotals{Bucket: job.Bucket, Objects: 10, Bytes: 100}); err != nil {
+		t.Fatalf("save totals: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Migration.Resume = true
+	cfg.Migration.RefreshCount = true
+	src := &stallingClient{MemoryClient: storage.NewMemoryClient(testBucket), started: make(chan struct{})}
+	core, logs := observer.New(zap.WarnLevel)
+	m := newTestMigrator(cfg, src, store, job)
+	m.logger = zap.New(core)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	objects, bytes, err := m.countJob(ctx, m.jobs[0])
+	if err != nil || objects != 10 || bytes != 100 {
+		t.Fatalf("countJob = %d, %d, %v, want the cached 10, 100", objects, bytes, err)
+	}
+	<-src.started
+
+	// What Run does when it returns
+	cancel()
+	m.counts.Wait()
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("close checkpoint: %v", err)
+	}
+	for _, entry := range logs.All() {
+		t.Errorf("unexpected warning after the run: %s", entry.Message)
+	}
+}
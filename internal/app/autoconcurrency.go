@@ -0,0 +1,177 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"time"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/progress"
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// workersPerCPU is the concurrency per CPU for objects large enough that
+	// transfers, not request latency, dominate
+	workersPerCPU = 4
+
+	// maxWorkersPerCPU and maxAutoConcurrency bound the concurrency chosen for
+	// small, latency-bound objects
+	maxWorkersPerCPU   = 32
+	maxAutoConcurrency = 256
+	minAutoConcurrency = 4
+
+	// The probe lists up to probeObjects objects to learn the typical object
+	// size, then downloads up to probeBytes of the largest one
+	probeObjects = 100
+	probeBytes   = 8 << 20
+	probeTimeout = 10 * time.Second
+)
+
+// sourceProbe is what a short probe of the source found
+type sourceProbe struct {
+	objects    int
+	avgSize    int64
+	latency    time.Duration // Time to the first byte of a GET
+	throughput float64       // Bytes per second of a single stream
+}
+
+// autoConcurrency picks the worker count for --auto-concurrency from the
+// number of CPUs and a short probe of the source. Each worker spends the
+// request latency plus the transfer time on an object; the more of that is
+// latency, the more workers it takes to keep the network busy, so the per-CPU
+// baseline is scaled by (latency + transfer) / transfer. The result is capped
+// so that part buffers stay within the memory check's budget.
+func autoConcurrency(ctx context.Context, cfg *config.Config, client storage.Client, jobs []migrationJob, logger *zap.Logger) int {
+	cpus := runtime.NumCPU()
+	concurrency := cpus * workersPerCPU
+	upper := cpus * maxWorkersPerCPU
+	if upper > maxAutoConcurrency {
+		upper = maxAutoConcurrency
+	}
+
+	fields := []zap.Field{zap.Int("cpus", cpus), zap.Int("configured", cfg.Migration.Concurrency)}
+	reason := "no probe, using the per-CPU baseline"
+
+	probe, err := probeSource(ctx, client, jobs[0])
+	switch {
+	case err != nil:
+		logger.Warn("Source probe failed, choosing concurrency from CPUs only", zap.Error(err))
+	case probe.objects == 0:
+		reason = "no objects to probe, using the per-CPU baseline"
+	default:
+		transfer := 0.0
+		if probe.throughput > 0 {
+			transfer = float64(probe.avgSize) / probe.throughput
+		}
+		factor := float64(upper) / float64(concurrency)
+		if transfer > 0 {
+			factor = math.Min(factor, (probe.latency.Seconds()+transfer)/transfer)
+		}
+		concurrency = int(math.Ceil(float64(concurrency) * factor))
+		reason = "per-CPU baseline scaled by the share of request latency in each object's transfer time"
+		fields = append(fields,
+			zap.Int("probed_objects", probe.objects),
+			zap.String("avg_object_size", progress.FormatBytes(probe.avgSize)),
+			zap.Duration("first_byte_latency", probe.latency),
+			zap.String("stream_throughput", progress.FormatSpeed(probe.throughput)),
+			zap.Float64("latency_factor", factor),
+		)
+	}
+
+	if concurrency > upper {
+		concurrency = upper
+	}
+	if concurrency < minAutoConcurrency {
+		concurrency = minAutoConcurrency
+	}
+
+	// Stay below the buffer memory warning of checkBufferMemory
+	if available, ok := availableMemory(); ok {
+		perWorker := estimateBufferMemory(cfg) / uint64(cfg.Migration.Concurrency)
+		if perWorker > 0 {
+			limit := int(float64(available) * memoryWarnFraction / float64(perWorker))
+			if limit < 1 {
+				limit = 1
+			}
+			if concurrency > limit {
+				concurrency = limit
+				reason += ", capped by available memory for part buffers"
+				fields = append(fields, zap.String("available_memory", progress.FormatBytes(int64(available))))
+			}
+		}
+	}
+
+	logger.Info("Chose concurrency automatically", append(fields,
+		zap.Int("concurrency", concurrency),
+		zap.String("reason", reason),
+	)...)
+	return concurrency
+}
+
+// probeSource lists the first objects of job and downloads the start of the
+// largest one, measuring the time to its first byte and the throughput of a
+// single stream
+func probeSource(ctx context.Context, client storage.Client, job migrationJob) (sourceProbe, error) {
+	listCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	var probe sourceProbe
+	var total int64
+	var largest storage.ObjectInfo
+	objCh, errCh := client.ListObjects(listCtx, job.Bucket, job.Prefix, storage.ListOptions{})
+	for probe.objects < probeObjects {
+		obj, ok, err := nextObject(listCtx, objCh, errCh)
+		if err != nil {
+			return probe, fmt.Errorf("failed to list objects: %w", err)
+		}
+		if !ok {
+			break
+		}
+		probe.objects++
+		total += obj.Size
+		if obj.Size > largest.Size {
+			largest = obj
+		}
+	}
+	// Stop the listing before the download
+	cancel()
+	if probe.objects == 0 {
+		return probe, nil
+	}
+	probe.avgSize = total / int64(probe.objects)
+	if largest.Size == 0 {
+		return probe, nil
+	}
+
+	ctx, cancelGet := context.WithTimeout(ctx, probeTimeout)
+	defer cancelGet()
+	start := time.Now()
+	obj, err := client.GetObject(ctx, job.Bucket, largest.Key, storage.GetOptions{})
+	if err != nil {
+		return probe, fmt.Errorf("failed to get %s: %w", largest.Key, err)
+	}
+	defer obj.Close()
+
+	// GETs are lazy, so the first read includes the request
+	first := make([]byte, 1)
+	if _, err := io.ReadFull(obj, first); err != nil {
+		return probe, fmt.Errorf("failed to read %s: %w", largest.Key, err)
+	}
+	probe.latency = time.Since(start)
+
+	start = time.Now()
+	n, err := io.CopyN(io.Discard, obj, probeBytes-1)
+	if err != nil && err != io.EOF && ctx.Err() == nil {
+		return probe, fmt.Errorf("failed to read %s: %w", largest.Key, err)
+	}
+	if elapsed := time.Since(start).Seconds(); n > 0 && elapsed > 0 {
+		probe.throughput = float64(n) / elapsed
+	}
+	return probe, nil
+}
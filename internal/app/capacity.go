@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"minio2rustfs/internal/progress"
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// ErrInsufficientCapacity is returned by --check-capacity when the objects
+// left to migrate do not fit in the destination's free space
+var ErrInsufficientCapacity = errors.New("insufficient destination capacity")
+
+// checkCapacity compares the bytes left to migrate with the free space of the
+// destination. Bytes restored from a previous run's progress are not counted
+// again, but objects that already exist on the destination are, so the check
+// errs on the side of caution. When the capacity cannot be determined, it
+// warns and lets the migration proceed.
+func (m *Migrator) checkCapacity(ctx context.Context, totalBytes int64) error {
+	reporter, ok := m.dstClient.(storage.CapacityReporter)
+	if !ok {
+		m.logger.Warn("Destination capacity cannot be determined for this target type, skipping capacity check")
+		return nil
+	}
+
+	capacity, err := reporter.Capacity(ctx)
+	if err != nil {
+		m.logger.Warn("Failed to determine destination capacity, skipping capacity check", zap.Error(err))
+		return nil
+	}
+
+	required := totalBytes - m.metrics.GetProgressTracker().GetStatus().ProcessedBytes
+	if required < 0 {
+		required = 0
+	}
+	fields := []zap.Field{
+		zap.String("required", progress.FormatBytes(required)),
+		zap.String("free", progress.FormatBytes(capacity.Free)),
+		zap.String("total", progress.FormatBytes(capacity.Total)),
+	}
+	if required > capacity.Free {
+		m.logger.Error("Destination does not have enough free space", fields...)
+		return fmt.Errorf("%w: %s to migrate, %s free on %s", ErrInsufficientCapacity,
+			progress.FormatBytes(required), progress.FormatBytes(capacity.Free), m.cfg.Target.Endpoint)
+	}
+	m.logger.Info("Destination capacity check passed", fields...)
+	return nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+// failedManifest collects the source keys of objects that failed after all
+// retries and writes them at the end of the run, one per line, in the format
+// read by --object-list, so that the failures can be replayed directly.
+// Workers report failures concurrently, so additions are serialized.
+type failedManifest struct {
+	path string
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+// newFailedManifest returns nil when path is empty
+func newFailedManifest(path string) *failedManifest {
+	if path == "" {
+		return nil
+	}
+	return &failedManifest{path: path, keys: make(map[string]bool)}
+}
+
+// add records the key of a failed task. A nil manifest records nothing.
+func (f *failedManifest) add(task worker.Task) {
+	if f == nil {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys[task.Key] = true
+}
+
+// write writes the recorded keys in key order. Nothing is written when no
+// object failed, so a manifest left by an earlier run is kept. Keys with a
+// line break cannot be expressed in the format and are logged instead.
+func (f *failedManifest) write(logger *zap.Logger) error {
+	if f == nil {
+		return nil
+	}
+
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.keys))
+	for key := range f.keys {
+		keys = append(keys, key)
+	}
+	f.mu.Unlock()
+
+	if len(keys) == 0 {
+		logger.Info("No objects failed, replay manifest not written", zap.String("path", f.path))
+		return nil
+	}
+	sort.Strings(keys)
+
+	file, err := os.Create(f.path)
+	if err != nil {
+		return fmt.Errorf("failed to create replay manifest: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	written := 0
+	for _, key := range keys {
+		if strings.ContainsAny(key, "\r\n") {
+			logger.Warn("Failed object cannot be written to the replay manifest", zap.String("key", key))
+			continue
+		}
+		w.WriteString(key)
+		w.WriteByte('\n')
+		written++
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write replay manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write replay manifest: %w", err)
+	}
+
+	logger.Info("Wrote replay manifest of failed objects; rerun them with --object-list",
+		zap.String("path", f.path),
+		zap.Int("objects", written),
+	)
+	return nil
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/storage"
+)
+
+// inventoryHeader is the first row of an --inventory file
+var inventoryHeader = []string{"bucket", "key", "size", "etag", "content_type", "last_modified", "metadata"}
+
+// inventoryWriter streams the source objects seen while listing to a CSV
+// file. It only uses what the listing already returned, so it costs no extra
+// requests. Jobs may be listed concurrently, so writes are serialized.
+type inventoryWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	csv  *csv.Writer
+}
+
+// newInventoryWriter creates (or truncates) the inventory file at path and
+// writes its header. It returns nil when path is empty.
+func newInventoryWriter(path string) (*inventoryWriter, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create inventory file: %w", err)
+	}
+	w := &inventoryWriter{file: file, csv: csv.NewWriter(file)}
+	if err := w.csv.Write(inventoryHeader); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write inventory file: %w", err)
+	}
+	return w, nil
+}
+
+// write appends obj of bucket. User metadata is written as a JSON object,
+// empty when the listing did not return any. A nil writer writes nothing.
+func (w *inventoryWriter) write(bucket string, obj storage.ObjectInfo) error {
+	if w == nil {
+		return nil
+	}
+
+	metadata := ""
+	if len(obj.Metadata) > 0 {
+		data, err := json.Marshal(obj.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata of %s: %w", obj.Key, err)
+		}
+		metadata = string(data)
+	}
+	lastModified := ""
+	if !obj.LastModified.IsZero() {
+		lastModified = obj.LastModified.UTC().Format(time.RFC3339)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.csv.Write([]string{
+		bucket,
+		obj.Key,
+		strconv.FormatInt(obj.Size, 10),
+		obj.ETag,
+		obj.ContentType,
+		lastModified,
+		metadata,
+	}); err != nil {
+		return fmt.Errorf("failed to write inventory file: %w", err)
+	}
+	return nil
+}
+
+// flush writes buffered rows to the file, so that the inventory of a finished
+// pass is complete on disk even while the process keeps running
+func (w *inventoryWriter) flush() error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return fmt.Errorf("failed to write inventory file: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the inventory file
+func (w *inventoryWriter) Close() error {
+	if w == nil {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
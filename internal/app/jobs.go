@@ -0,0 +1,26 @@
+package app
+
+import (
+	"minio2rustfs/internal/config"
+)
+
+// migrationJob is a configured job together with the key mapper deriving its
+// destination bucket and keys
+type migrationJob struct {
+	config.JobSpec
+	keys *keyMapper
+}
+
+// newJobs builds the jobs of cfg, parsing the key template up front so that a
+// bad template fails before any work starts
+func newJobs(cfg *config.Config) ([]migrationJob, error) {
+	jobs := make([]migrationJob, 0, len(cfg.Migration.Jobs))
+	for _, spec := range cfg.Migration.Jobs {
+		keys, err := newKeyMapper(cfg, spec)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, migrationJob{JobSpec: spec, keys: keys})
+	}
+	return jobs, nil
+}
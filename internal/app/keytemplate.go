@@ -0,0 +1,171 @@
+package app
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/worker"
+)
+
+// keyMapper derives destination keys from source keys by stripping the strip
+// prefix, applying the key template, if any, and then prepending the
+// destination prefix. It also sets the destination bucket of a job.
+type keyMapper struct {
+	bucket    string // Destination bucket; "" keeps the source bucket
+	strip     string // Normalized to end with "/"
+	skipStrip bool   // Skip keys outside strip instead of failing
+	template  *template.Template
+	prefix    string
+}
+
+// newKeyMapper builds the key mapper for job. It returns nil when destination
+// buckets and keys equal the source ones.
+func newKeyMapper(cfg *config.Config, job config.JobSpec) (*keyMapper, error) {
+	var bucket string
+	if job.DstBucket != job.Bucket {
+		bucket = job.DstBucket
+	}
+	if bucket == "" && cfg.Migration.KeyTemplate == "" && job.DstPrefix == "" && cfg.Migration.StripPrefix == "" {
+		return nil, nil
+	}
+
+	k := &keyMapper{bucket: bucket, prefix: job.DstPrefix, skipStrip: cfg.Migration.StripPrefixSkip}
+	if cfg.Migration.StripPrefix != "" {
+		k.strip = joinKeyPrefix(cfg.Migration.StripPrefix, "")
+	}
+	if cfg.Migration.KeyTemplate != "" {
+		tmpl, err := parseKeyTemplate(cfg.Migration.KeyTemplate)
+		if err != nil {
+			return nil, err
+		}
+		k.template = tmpl
+	}
+	return k, nil
+}
+
+// apply sets the destination bucket and key of task. It returns false when the task
+// should be skipped because its key lies outside the strip prefix and skipping
+// such keys is enabled. A nil mapper leaves task unchanged.
+func (k *keyMapper) apply(task *worker.Task) (bool, error) {
+	if k == nil {
+		return true, nil
+	}
+
+	dstKey := task.DestinationKey()
+	if k.strip != "" {
+		stripped := strings.TrimPrefix(dstKey, k.strip)
+		if stripped == dstKey || stripped == "" {
+			if k.skipStrip {
+				return false, nil
+			}
+			return false, fmt.Errorf("key %s does not start with strip prefix %s", dstKey, k.strip)
+		}
+		dstKey = stripped
+	}
+	if k.template != nil {
+		rendered, err := renderKey(k.template, *task)
+		if err != nil {
+			return false, fmt.Errorf("failed to render destination key for %s: %w", task.Key, err)
+		}
+		dstKey = rendered
+	}
+	dstKey = joinKeyPrefix(k.prefix, dstKey)
+
+	if k.bucket != "" {
+		task.DstBucket = k.bucket
+	}
+	if dstKey != task.Key {
+		task.DstKey = dstKey
+	}
+	return true, nil
+}
+
+// destinationBucket returns the destination bucket for source bucket
+func (k *keyMapper) destinationBucket(bucket string) string {
+	if k == nil || k.bucket == "" {
+		return bucket
+	}
+	return k.bucket
+}
+
+// joinKeyPrefix prepends prefix to key with exactly one slash between them
+func joinKeyPrefix(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(prefix, "/") + "/" + strings.TrimPrefix(key, "/")
+}
+
+// KeyTemplateData holds the variables available to --key-template
+type KeyTemplateData struct {
+	Bucket       string
+	Key          string // Original source key
+	Dir          string // Key without its last element, "" for top-level keys
+	Base         string // Last element of the key
+	Ext          string // Extension of the last element, including the dot
+	Size         int64
+	LastModified time.Time
+	Year         string // Last-modified components in UTC, zero-padded
+	Month        string
+	Day          string
+	Hour         string
+}
+
+// parseKeyTemplate parses a destination key template and renders it once
+// against sample data, so that errors surface at startup instead of per object.
+func parseKeyTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("key").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	sample := worker.Task{
+		Bucket:       "bucket",
+		Key:          "dir/object.txt",
+		Size:         1,
+		LastModified: time.Now(),
+	}
+	if _, err := renderKey(tmpl, sample); err != nil {
+		return nil, fmt.Errorf("invalid key template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderKey evaluates the key template for a task
+func renderKey(tmpl *template.Template, task worker.Task) (string, error) {
+	dir := path.Dir(task.Key)
+	if dir == "." {
+		dir = ""
+	}
+	modified := task.LastModified.UTC()
+
+	data := KeyTemplateData{
+		Bucket:       task.Bucket,
+		Key:          task.Key,
+		Dir:          dir,
+		Base:         path.Base(task.Key),
+		Ext:          path.Ext(task.Key),
+		Size:         task.Size,
+		LastModified: modified,
+		Year:         fmt.Sprintf("%04d", modified.Year()),
+		Month:        fmt.Sprintf("%02d", int(modified.Month())),
+		Day:          fmt.Sprintf("%02d", modified.Day()),
+		Hour:         fmt.Sprintf("%02d", modified.Hour()),
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	key := strings.TrimPrefix(b.String(), "/")
+	if key == "" {
+		return "", fmt.Errorf("key template rendered an empty key for %q", task.Key)
+	}
+	return key, nil
+}
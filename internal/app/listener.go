@@ -0,0 +1,173 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/storage"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+const (
+	eventObjectCreated = "s3:ObjectCreated:"
+	eventObjectRemoved = "s3:ObjectRemoved:"
+)
+
+// listen subscribes to source bucket notifications and migrates objects as
+// they are created, until the context is cancelled. Objects changed between
+// the initial pass and the subscription are caught up by an incremental pass.
+func (m *Migrator) listen(ctx context.Context, since time.Time) error {
+	// Listening is limited to a single job by config validation
+	bucket, prefix := m.jobs[0].Bucket, m.jobs[0].Prefix
+	eventTypes := m.listenEventTypes()
+
+	m.logger.Info("Listening for bucket notifications",
+		zap.String("bucket", bucket),
+		zap.String("prefix", prefix),
+		zap.Strings("events", eventTypes),
+		zap.Bool("mirror", m.cfg.Migration.Mirror),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, errs := m.srcClient.ListenBucketNotification(ctx, bucket, prefix, eventTypes)
+
+	tasks := make(chan worker.Task, m.cfg.Migration.TaskQueueDepth())
+	var wg sync.WaitGroup
+	m.workers.Start(ctx, tasks, &wg)
+
+	catchUp := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		catchUp <- m.runPass(ctx, since)
+	}()
+
+	// Stop the catch-up pass and drain workers before returning
+	defer func() {
+		cancel()
+		close(tasks)
+		m.waitForWorkers(&wg)
+		m.workers.Flush(context.Background())
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("bucket notification stream closed")
+			}
+			if err := m.handleEvent(ctx, bucket, event, tasks); err != nil {
+				m.logger.Info("Listen mode stopped")
+				return nil
+			}
+
+		case err := <-errs:
+			if err != nil {
+				return fmt.Errorf("error listening for bucket notifications: %w", err)
+			}
+
+		case err := <-catchUp:
+			if err != nil {
+				return err
+			}
+			m.logger.Info("Catch-up pass completed")
+
+		case <-ctx.Done():
+			m.logger.Info("Listen mode stopped")
+			return nil
+		}
+	}
+}
+
+// listenEventTypes returns the configured event filter, adding removal events when mirroring
+func (m *Migrator) listenEventTypes() []string {
+	eventTypes := append([]string(nil), m.cfg.Migration.ListenEvents...)
+	if !m.cfg.Migration.Mirror {
+		return eventTypes
+	}
+
+	for _, eventType := range eventTypes {
+		if strings.HasPrefix(eventType, eventObjectRemoved) {
+			return eventTypes
+		}
+	}
+	return append(eventTypes, eventObjectRemoved+"*")
+}
+
+// handleEvent enqueues created objects and, with mirror on, propagates deletions.
+// It only returns an error when the context is cancelled.
+func (m *Migrator) handleEvent(ctx context.Context, bucket string, event storage.Event, tasks chan<- worker.Task) error {
+	switch {
+	case strings.HasPrefix(event.Name, eventObjectCreated):
+		if !m.cfg.Migration.AllowWeirdKeys && isOddKey(event.Key) {
+			m.logger.Warn("Skipping object with empty or slash-only key", zap.String("key", event.Key))
+			return nil
+		}
+
+		task := worker.Task{
+			Bucket:       bucket,
+			Key:          event.Key,
+			Size:         event.Size,
+			ETag:         event.ETag,
+			ContentType:  event.ContentType,
+			Metadata:     event.Metadata,
+			LastModified: event.LastModified,
+		}
+		m.metadata.apply(&task)
+		if keep, err := m.jobs[0].keys.apply(&task); err != nil {
+			m.logger.Error("Failed to derive destination key", zap.String("key", event.Key), zap.Error(err))
+			return nil
+		} else if !keep {
+			return nil
+		}
+
+		if m.cfg.Migration.DryRun {
+			m.logger.Info("Would migrate object",
+				zap.String("bucket", bucket),
+				zap.String("key", event.Key),
+				zap.String("dst_key", task.DestinationKey()),
+				zap.Int64("size", event.Size),
+			)
+			return nil
+		}
+
+		select {
+		case tasks <- task:
+			m.logger.Debug("Enqueued object from event", zap.String("key", event.Key), zap.String("event", event.Name))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	case strings.HasPrefix(event.Name, eventObjectRemoved) && m.cfg.Migration.Mirror:
+		// Mirror cannot be combined with a key template, so the destination key
+		// only depends on the source key
+		task := worker.Task{Bucket: bucket, Key: event.Key}
+		if keep, err := m.jobs[0].keys.apply(&task); err != nil {
+			m.logger.Error("Failed to derive destination key", zap.String("key", event.Key), zap.Error(err))
+			return nil
+		} else if !keep {
+			return nil
+		}
+		dstBucket, dstKey := task.DestinationBucket(), task.DestinationKey()
+
+		if m.cfg.Migration.DryRun {
+			m.logger.Info("Would delete object", zap.String("bucket", dstBucket), zap.String("key", dstKey))
+			return nil
+		}
+
+		if err := m.dstClient.RemoveObject(ctx, dstBucket, dstKey); err != nil {
+			m.logger.Error("Failed to propagate deletion", zap.String("key", dstKey), zap.Error(err))
+			return nil
+		}
+		m.logger.Info("Propagated deletion", zap.String("key", dstKey))
+	}
+
+	return nil
+}
@@ -3,7 +3,12 @@ package app
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/config"
 	"minio2rustfs/internal/storage"
 	"minio2rustfs/internal/worker"
 
@@ -12,8 +17,32 @@ import (
 
 // ObjectLister handles listing objects for migration
 type ObjectLister struct {
-	client storage.Client
-	logger *zap.Logger
+	client        storage.Client
+	logger        *zap.Logger
+	modifiedSince time.Time           // When set, objects not modified after this time are skipped
+	countWorkers  int                 // Concurrent counters used by CountObjects; <= 1 counts in a single listing
+	listOpts      storage.ListOptions // Options for every source (and compare) listing
+	keys          *keyMapper          // Derives destination keys; nil keeps source keys
+	metadata      *metadataTransform  // Edits user metadata; nil keeps it unchanged
+	inventory     *inventoryWriter    // Records every listed source object; nil records nothing
+	ranges        []config.RangeEntry // When set, only these byte ranges are migrated instead of listing
+	objectKeys    []string            // When set, only these objects are migrated instead of listing
+	allowOddKeys  bool                // Enqueue empty and slash-only keys instead of skipping them
+	layoutOrder   bool                // List in the source's storage layout order when it exposes one
+	onListed      func(size int64)    // Called for every object found while counting or listing; may run concurrently
+
+	// With compareClient set, the destination is listed alongside the source
+	// and only new or changed objects are enqueued; onUnchanged is called for
+	// every object that is skipped because it already matches.
+	compareClient storage.Client
+	onUnchanged   func(storage.ObjectInfo)
+
+	// With listState set, the source is listed page by page and each page's
+	// continuation token is saved together with pending records of its
+	// objects. With resumeListing set, an interrupted listing continues at the
+	// saved token instead of starting over.
+	listState     checkpoint.Store
+	resumeListing bool
 }
 
 // ListAndEnqueue lists objects and enqueues them as tasks
@@ -22,8 +51,20 @@ func (l *ObjectLister) ListAndEnqueue(ctx context.Context, bucket, prefix, objec
 		// Single object mode
 		return l.enqueueSingleObject(ctx, bucket, objectKey, tasks, dryRun)
 	}
+	if l.ranges != nil {
+		return l.enqueueRanges(ctx, bucket, tasks, dryRun)
+	}
+	if l.objectKeys != nil {
+		return l.enqueueObjectList(ctx, bucket, tasks, dryRun)
+	}
 
 	// List objects with prefix
+	if l.compareClient != nil {
+		return l.enqueueChangedObjects(ctx, bucket, prefix, tasks, dryRun)
+	}
+	if l.listState != nil {
+		return l.enqueueObjectPages(ctx, bucket, prefix, tasks, dryRun)
+	}
 	return l.enqueueObjects(ctx, bucket, prefix, tasks, dryRun)
 }
 
@@ -37,13 +78,103 @@ func (l *ObjectLister) CountObjects(ctx context.Context, bucket, prefix, objectK
 		}
 		return 1, info.Size, nil
 	}
+	if l.ranges != nil {
+		var totalObjects, totalSize int64
+		for _, entry := range l.ranges {
+			task, keep, err := l.rangeTask(ctx, bucket, entry)
+			if err != nil {
+				return 0, 0, err
+			}
+			if keep {
+				totalObjects++
+				totalSize += task.Size
+			}
+		}
+		return totalObjects, totalSize, nil
+	}
+	if l.objectKeys != nil {
+		var totalObjects, totalSize int64
+		for _, key := range l.objectKeys {
+			info, err := l.client.HeadObject(ctx, bucket, key)
+			if storage.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to get object info for %s: %w", key, err)
+			}
+			totalObjects++
+			totalSize += info.Size
+		}
+		return totalObjects, totalSize, nil
+	}
 
-	// Count objects with prefix
+	// Count objects with prefix. A non-recursive listing has no shards below
+	// the prefix to split by.
+	if l.countWorkers > 1 && !l.listOpts.NonRecursive {
+		return l.countObjectsSharded(ctx, bucket, prefix)
+	}
 	return l.countObjects(ctx, bucket, prefix)
 }
 
+// countObjectsSharded splits the listing by the common prefixes directly under
+// prefix and counts each shard concurrently, summing the results.
+func (l *ObjectLister) countObjectsSharded(ctx context.Context, bucket, prefix string) (int64, int64, error) {
+	shards, objects, err := l.client.ListPrefixes(ctx, bucket, prefix)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error listing prefixes: %w", err)
+	}
+
+	var mu sync.Mutex
+	var totalObjects, totalSize int64
+	var firstErr error
+
+	totalObjects = int64(len(objects))
+	for _, obj := range objects {
+		totalSize += obj.Size
+	}
+
+	l.logger.Debug("Counting objects concurrently",
+		zap.Int("shards", len(shards)),
+		zap.Int("workers", l.countWorkers),
+	)
+
+	shardCh := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < l.countWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for shard := range shardCh {
+				objects, size, err := l.countObjects(ctx, bucket, shard)
+
+				mu.Lock()
+				totalObjects += objects
+				totalSize += size
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, shard := range shards {
+		select {
+		case shardCh <- shard:
+		case <-ctx.Done():
+		}
+	}
+	close(shardCh)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return totalObjects, totalSize, firstErr
+}
+
 func (l *ObjectLister) countObjects(ctx context.Context, bucket, prefix string) (int64, int64, error) {
-	objCh, errCh := l.client.ListObjects(ctx, bucket, prefix)
+	objCh, errCh := l.client.ListObjects(ctx, bucket, prefix, l.listOpts)
 
 	var totalObjects int64
 	var totalSize int64
@@ -57,6 +188,7 @@ func (l *ObjectLister) countObjects(ctx context.Context, bucket, prefix string)
 
 			totalObjects++
 			totalSize += obj.Size
+			l.listed(obj.Size)
 
 		case err := <-errCh:
 			if err != nil {
@@ -69,25 +201,105 @@ func (l *ObjectLister) countObjects(ctx context.Context, bucket, prefix string)
 	}
 }
 
+// CaseConflict is a group of keys that differ only by letter case
+type CaseConflict struct {
+	Keys []string
+}
+
+// FindCaseConflicts lists all objects under prefix and returns groups of keys
+// that would collide on a destination treating keys case-insensitively.
+// It keeps every lowercased key in memory for the duration of the scan.
+func (l *ObjectLister) FindCaseConflicts(ctx context.Context, bucket, prefix string) ([]CaseConflict, error) {
+	objCh, errCh := l.client.ListObjects(ctx, bucket, prefix, l.listOpts)
+
+	seen := make(map[string][]string)
+	var conflicting []string
+
+	for {
+		select {
+		case obj, ok := <-objCh:
+			if !ok {
+				conflicts := make([]CaseConflict, 0, len(conflicting))
+				for _, folded := range conflicting {
+					conflicts = append(conflicts, CaseConflict{Keys: seen[folded]})
+				}
+				return conflicts, nil
+			}
+
+			folded := strings.ToLower(obj.Key)
+			keys := seen[folded]
+			if len(keys) == 1 {
+				conflicting = append(conflicting, folded)
+			}
+			seen[folded] = append(keys, obj.Key)
+
+		case err := <-errCh:
+			if err != nil {
+				return nil, fmt.Errorf("error listing objects: %w", err)
+			}
+
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// listed reports an object found by the listing to onListed, if set
+func (l *ObjectLister) listed(size int64) {
+	if l.onListed != nil {
+		l.onListed(size)
+	}
+}
+
+// isOddKey reports whether key is empty or consists only of slashes. Some S3
+// implementations list such keys, but they cannot be written back reliably
+// and clash in the checkpoint.
+func isOddKey(key string) bool {
+	return strings.Trim(key, "/") == ""
+}
+
+// skipsOddKey reports whether key is skipped for being empty or slash-only,
+// logging each skipped key
+func (l *ObjectLister) skipsOddKey(key string) bool {
+	if l.allowOddKeys || !isOddKey(key) {
+		return false
+	}
+	l.logger.Warn("Skipping object with empty or slash-only key", zap.String("key", key))
+	return true
+}
+
 func (l *ObjectLister) enqueueSingleObject(ctx context.Context, bucket, key string, tasks chan<- worker.Task, dryRun bool) error {
 	info, err := l.client.HeadObject(ctx, bucket, key)
 	if err != nil {
 		return fmt.Errorf("failed to get object info for %s: %w", key, err)
 	}
 
+	if !l.modifiedSince.IsZero() && !info.LastModified.After(l.modifiedSince) {
+		return nil
+	}
+
 	task := worker.Task{
-		Bucket:      bucket,
-		Key:         key,
-		Size:        info.Size,
-		ETag:        info.ETag,
-		ContentType: info.ContentType, // Add ContentType field
-		Metadata:    info.Metadata,
+		Bucket:       bucket,
+		Key:          key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType, // Add ContentType field
+		Metadata:     info.Metadata,
+		LastModified: info.LastModified,
+	}
+	l.metadata.apply(&task)
+	if keep, err := l.keys.apply(&task); err != nil {
+		return err
+	} else if !keep {
+		l.logger.Info("Skipping object outside strip prefix", zap.String("key", key))
+		return nil
 	}
 
 	if dryRun {
 		l.logger.Info("Would migrate object",
 			zap.String("bucket", bucket),
 			zap.String("key", key),
+			zap.String("dst_key", task.DestinationKey()),
 			zap.Int64("size", info.Size),
 		)
 		return nil
@@ -103,11 +315,118 @@ func (l *ObjectLister) enqueueSingleObject(ctx context.Context, bucket, key stri
 	return nil
 }
 
+// enqueueObjectList enqueues the objects of an object list. Objects deleted
+// from the source since the list was written are logged and skipped.
+func (l *ObjectLister) enqueueObjectList(ctx context.Context, bucket string, tasks chan<- worker.Task, dryRun bool) error {
+	var missing int
+	for _, key := range l.objectKeys {
+		err := l.enqueueSingleObject(ctx, bucket, key, tasks, dryRun)
+		if storage.IsNotFound(err) {
+			l.logger.Warn("Skipping listed object missing from source", zap.String("bucket", bucket), zap.String("key", key))
+			missing++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	l.logger.Info("Finished enqueueing object list",
+		zap.Int("listed_keys", len(l.objectKeys)),
+		zap.Int("missing", missing),
+	)
+	return nil
+}
+
+// enqueueRanges enqueues one task per range manifest entry
+func (l *ObjectLister) enqueueRanges(ctx context.Context, bucket string, tasks chan<- worker.Task, dryRun bool) error {
+	for _, entry := range l.ranges {
+		task, keep, err := l.rangeTask(ctx, bucket, entry)
+		if err != nil {
+			return err
+		}
+		if !keep {
+			continue
+		}
+
+		if dryRun {
+			l.logger.Info("Would migrate object range",
+				zap.String("bucket", bucket),
+				zap.String("key", entry.Key),
+				zap.Int64("offset", task.Range.Offset),
+				zap.Int64("length", task.Size),
+				zap.String("dst_key", task.DestinationKey()),
+			)
+			continue
+		}
+
+		select {
+		case tasks <- task:
+			l.logger.Debug("Enqueued object range", zap.String("key", entry.Key), zap.Int64("offset", task.Range.Offset))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// rangeTask builds the task for a range manifest entry, checking the range
+// against the current size of the source object. It reports false when the
+// entry is skipped for lying outside the strip prefix.
+func (l *ObjectLister) rangeTask(ctx context.Context, bucket string, entry config.RangeEntry) (worker.Task, bool, error) {
+	info, err := l.client.HeadObject(ctx, bucket, entry.Key)
+	if err != nil {
+		return worker.Task{}, false, fmt.Errorf("failed to get object info for %s: %w", entry.Key, err)
+	}
+
+	length := entry.Length
+	if length == 0 {
+		length = info.Size - entry.Offset
+	}
+	if length <= 0 || entry.Offset+length > info.Size {
+		return worker.Task{}, false, fmt.Errorf("range %d+%d is outside %s (%d bytes)", entry.Offset, length, entry.Key, info.Size)
+	}
+
+	dstKey := entry.DstKey
+	if dstKey == "" {
+		dstKey = fmt.Sprintf("%s.%d-%d", entry.Key, entry.Offset, entry.Offset+length-1)
+	}
+
+	task := worker.Task{
+		Bucket:       bucket,
+		Key:          entry.Key,
+		Size:         length,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		Metadata:     info.Metadata,
+		LastModified: info.LastModified,
+		DstKey:       dstKey,
+		Range:        &worker.ByteRange{Offset: entry.Offset},
+	}
+	l.metadata.apply(&task)
+	keep, err := l.keys.apply(&task)
+	if err != nil {
+		return worker.Task{}, false, err
+	}
+	return task, keep, nil
+}
+
+// listObjects lists the source in layout order when requested and supported,
+// and in key order otherwise
+func (l *ObjectLister) listObjects(ctx context.Context, bucket, prefix string) (<-chan storage.ObjectInfo, <-chan error) {
+	if layout, ok := l.client.(storage.LayoutLister); ok && l.layoutOrder {
+		return layout.ListObjectsByLayout(ctx, bucket, prefix, l.listOpts)
+	}
+	return l.client.ListObjects(ctx, bucket, prefix, l.listOpts)
+}
+
 func (l *ObjectLister) enqueueObjects(ctx context.Context, bucket, prefix string, tasks chan<- worker.Task, dryRun bool) error {
-	objCh, errCh := l.client.ListObjects(ctx, bucket, prefix)
+	objCh, errCh := l.listObjects(ctx, bucket, prefix)
 
 	var totalObjects int64
 	var totalSize int64
+	var oddKeys int64
 
 	for {
 		select {
@@ -116,26 +435,48 @@ func (l *ObjectLister) enqueueObjects(ctx context.Context, bucket, prefix string
 				l.logger.Info("Finished listing objects",
 					zap.Int64("total_objects", totalObjects),
 					zap.Int64("total_size_bytes", totalSize),
+					zap.Int64("skipped_odd_keys", oddKeys),
 				)
 				return nil
 			}
 
+			if l.skipsOddKey(obj.Key) {
+				oddKeys++
+				continue
+			}
+
+			if !l.modifiedSince.IsZero() && !obj.LastModified.After(l.modifiedSince) {
+				continue
+			}
+
 			totalObjects++
 			totalSize += obj.Size
+			l.listed(obj.Size)
+			if err := l.inventory.write(bucket, obj); err != nil {
+				return err
+			}
 
 			task := worker.Task{
-				Bucket:      bucket,
-				Key:         obj.Key,
-				Size:        obj.Size,
-				ETag:        obj.ETag,
-				ContentType: obj.ContentType, // Add ContentType field
-				Metadata:    obj.Metadata,
+				Bucket:       bucket,
+				Key:          obj.Key,
+				Size:         obj.Size,
+				ETag:         obj.ETag,
+				ContentType:  obj.ContentType, // Add ContentType field
+				Metadata:     obj.Metadata,
+				LastModified: obj.LastModified,
+			}
+			l.metadata.apply(&task)
+			if keep, err := l.keys.apply(&task); err != nil {
+				return err
+			} else if !keep {
+				continue
 			}
 
 			if dryRun {
 				l.logger.Info("Would migrate object",
 					zap.String("bucket", bucket),
 					zap.String("key", obj.Key),
+					zap.String("dst_key", task.DestinationKey()),
 					zap.Int64("size", obj.Size),
 				)
 				continue
@@ -158,3 +499,281 @@ func (l *ObjectLister) enqueueObjects(ctx context.Context, bucket, prefix string
 		}
 	}
 }
+
+// enqueueObjectPages lists objects page by page with continuation tokens.
+// Before a page is enqueued, its objects are recorded as pending and the token
+// of the next page is saved, so that a resumed listing continues after the
+// last saved page: objects of earlier pages that did not complete are
+// enqueued from their pending or failed records, and the listing goes on from
+// the token. Dry runs record nothing.
+func (l *ObjectLister) enqueueObjectPages(ctx context.Context, bucket, prefix string, tasks chan<- worker.Task, dryRun bool) error {
+	token := ""
+	var resumed map[string]bool // Keys enqueued from records, not enqueued again when listed
+	if l.resumeListing {
+		var err error
+		if token, err = l.listState.GetListToken(bucket, prefix); err != nil {
+			return fmt.Errorf("failed to read saved list token: %w", err)
+		}
+		if token != "" {
+			l.logger.Info("Resuming listing from saved continuation token",
+				zap.String("bucket", bucket),
+				zap.String("prefix", prefix),
+			)
+			if resumed, err = l.enqueueUnfinished(ctx, bucket, prefix, tasks, dryRun); err != nil {
+				return err
+			}
+		}
+	}
+
+	var totalObjects int64
+	var totalSize int64
+	var oddKeys int64
+	for {
+		page, err := l.client.ListObjectsPage(ctx, bucket, prefix, token)
+		if err != nil {
+			return fmt.Errorf("error listing objects: %w", err)
+		}
+
+		var pageTasks []worker.Task
+		for _, obj := range page.Objects {
+			if l.skipsOddKey(obj.Key) {
+				oddKeys++
+				continue
+			}
+			if !l.modifiedSince.IsZero() && !obj.LastModified.After(l.modifiedSince) {
+				continue
+			}
+
+			totalObjects++
+			totalSize += obj.Size
+			l.listed(obj.Size)
+			if err := l.inventory.write(bucket, obj); err != nil {
+				return err
+			}
+
+			task := worker.Task{
+				Bucket:       bucket,
+				Key:          obj.Key,
+				Size:         obj.Size,
+				ETag:         obj.ETag,
+				ContentType:  obj.ContentType,
+				Metadata:     obj.Metadata,
+				LastModified: obj.LastModified,
+			}
+			l.metadata.apply(&task)
+			if keep, err := l.keys.apply(&task); err != nil {
+				return err
+			} else if keep {
+				pageTasks = append(pageTasks, task)
+			}
+		}
+
+		if !dryRun {
+			records := make([]*checkpoint.TaskRecord, 0, len(pageTasks))
+			for _, task := range pageTasks {
+				records = append(records, &checkpoint.TaskRecord{
+					Bucket: task.Bucket,
+					Key:    task.CheckpointKey(),
+					Size:   task.Size,
+					ETag:   task.ETag,
+				})
+			}
+			if err := l.listState.SaveListPage(bucket, prefix, page.NextToken, records); err != nil {
+				return fmt.Errorf("failed to save listed page: %w", err)
+			}
+		}
+
+		for _, task := range pageTasks {
+			if resumed[task.Key] {
+				continue
+			}
+			if dryRun {
+				l.logger.Info("Would migrate object",
+					zap.String("bucket", bucket),
+					zap.String("key", task.Key),
+					zap.String("dst_key", task.DestinationKey()),
+					zap.Int64("size", task.Size),
+				)
+				continue
+			}
+
+			select {
+			case tasks <- task:
+				l.logger.Debug("Enqueued object", zap.String("key", task.Key))
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if page.NextToken == "" {
+			l.logger.Info("Finished listing objects",
+				zap.Int64("total_objects", totalObjects),
+				zap.Int64("total_size_bytes", totalSize),
+				zap.Int64("skipped_odd_keys", oddKeys),
+			)
+			return nil
+		}
+		token = page.NextToken
+	}
+}
+
+// enqueueUnfinished enqueues the objects recorded as pending or failed under
+// bucket/prefix, looking each one up on the source again, and returns their
+// keys. Objects that no longer exist are logged and left out.
+func (l *ObjectLister) enqueueUnfinished(ctx context.Context, bucket, prefix string, tasks chan<- worker.Task, dryRun bool) (map[string]bool, error) {
+	pending, err := l.listState.ListPendingTasksByBucket(bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending tasks: %w", err)
+	}
+	failed, err := l.listState.ListFailedTasksByBucket(bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list failed tasks: %w", err)
+	}
+
+	l.logger.Info("Enqueueing unfinished tasks of listed pages",
+		zap.Int("pending", len(pending)),
+		zap.Int("failed", len(failed)),
+	)
+
+	keys := make(map[string]bool, len(pending)+len(failed))
+	for _, record := range append(pending, failed...) {
+		keys[record.Key] = true
+		err := l.enqueueSingleObject(ctx, bucket, record.Key, tasks, dryRun)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			l.logger.Warn("Cannot enqueue unfinished task", zap.String("key", record.Key), zap.Error(err))
+		}
+	}
+	return keys, nil
+}
+
+// enqueueChangedObjects lists source and destination together and merges the
+// two key-ordered streams, enqueueing only objects that are missing on the
+// destination or differ in size/etag. This replaces a HEAD per object.
+func (l *ObjectLister) enqueueChangedObjects(ctx context.Context, bucket, prefix string, tasks chan<- worker.Task, dryRun bool) error {
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	srcCh, srcErrCh := l.client.ListObjects(listCtx, bucket, prefix, l.listOpts)
+	// Destination keys carry the destination prefix, which is stripped before
+	// comparing; the common prefix keeps both listings in the same key order
+	var dstPrefix string
+	if l.keys != nil && l.keys.prefix != "" {
+		dstPrefix = joinKeyPrefix(l.keys.prefix, "")
+	}
+	dstCh, dstErrCh := l.compareClient.ListObjects(listCtx, l.keys.destinationBucket(bucket), dstPrefix+prefix, l.listOpts)
+
+	nextDst := func() (storage.ObjectInfo, bool, error) {
+		obj, ok, err := nextObject(listCtx, dstCh, dstErrCh)
+		obj.Key = strings.TrimPrefix(obj.Key, dstPrefix)
+		return obj, ok, err
+	}
+
+	dst, dstOK, err := nextDst()
+	if err != nil {
+		return fmt.Errorf("error listing destination objects: %w", err)
+	}
+
+	var changedObjects, unchangedObjects int64
+	for {
+		obj, ok, err := nextObject(listCtx, srcCh, srcErrCh)
+		if err != nil {
+			return fmt.Errorf("error listing objects: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		// Advance the destination stream up to the current source key
+		for dstOK && dst.Key < obj.Key {
+			dst, dstOK, err = nextDst()
+			if err != nil {
+				return fmt.Errorf("error listing destination objects: %w", err)
+			}
+		}
+
+		if !l.modifiedSince.IsZero() && !obj.LastModified.After(l.modifiedSince) {
+			continue
+		}
+
+		if l.skipsOddKey(obj.Key) {
+			continue
+		}
+		l.listed(obj.Size)
+		if err := l.inventory.write(bucket, obj); err != nil {
+			return err
+		}
+
+		if dstOK && dst.Key == obj.Key && dst.Size == obj.Size && etagsMatch(obj.ETag, dst.ETag) {
+			unchangedObjects++
+			if l.onUnchanged != nil {
+				l.onUnchanged(obj)
+			}
+			continue
+		}
+
+		changedObjects++
+		task := worker.Task{
+			Bucket:       bucket,
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			ContentType:  obj.ContentType,
+			Metadata:     obj.Metadata,
+			LastModified: obj.LastModified,
+		}
+		l.metadata.apply(&task)
+		if keep, err := l.keys.apply(&task); err != nil {
+			return err
+		} else if !keep {
+			continue
+		}
+
+		if dryRun {
+			l.logger.Info("Would migrate object",
+				zap.String("bucket", bucket),
+				zap.String("key", obj.Key),
+				zap.Int64("size", obj.Size),
+			)
+			continue
+		}
+
+		select {
+		case tasks <- task:
+			l.logger.Debug("Enqueued changed object", zap.String("key", obj.Key))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	l.logger.Info("Finished comparing source and destination listings",
+		zap.Int64("changed_objects", changedObjects),
+		zap.Int64("unchanged_objects", unchangedObjects),
+	)
+	return nil
+}
+
+// nextObject receives the next object from a listing, returning false once the
+// listing is exhausted
+func nextObject(ctx context.Context, objCh <-chan storage.ObjectInfo, errCh <-chan error) (storage.ObjectInfo, bool, error) {
+	for {
+		select {
+		case obj, ok := <-objCh:
+			return obj, ok, nil
+
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			if err != nil {
+				return storage.ObjectInfo{}, false, err
+			}
+
+		case <-ctx.Done():
+			return storage.ObjectInfo{}, false, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+const testBucket = "bucket"
+
+// oddKeyTests are keys that some S3 implementations list, with whether they
+// are skipped by default
+var oddKeyTests = []struct {
+	key string
+	odd bool
+}{
+	{key: "", odd: true},
+	{key: "/", odd: true},
+	{key: "//", odd: true},
+	{key: "///", odd: true},
+	{key: "/leading-slash", odd: false},
+	{key: "double//slash", odd: false},
+	{key: "//leading-double", odd: false},
+	{key: "trailing//", odd: false},
+	{key: "./dot", odd: false},
+	{key: "dir/./dot", odd: false},
+	{key: "../dotdot", odd: false},
+	{key: "dir/../dotdot", odd: false},
+	{key: ".", odd: false},
+	{key: "..", odd: false},
+	{key: "control\x01char", odd: false},
+	{key: "tab\tkey", odd: false},
+	{key: "new\nline", odd: false},
+	{key: "del\x7fchar", odd: false},
+}
+
+func TestIsOddKey(t *testing.T) {
+	for _, tt := range oddKeyTests {
+		if got := isOddKey(tt.key); got != tt.odd {
+			t.Errorf("isOddKey(%q) = %v, want %v", tt.key, got, tt.odd)
+		}
+	}
+}
+
+// listTasks runs lister over the whole bucket and returns the enqueued keys
+func listTasks(t *testing.T, lister *ObjectLister) []string {
+	t.Helper()
+	tasks := make(chan worker.Task, len(oddKeyTests))
+	if err := lister.ListAndEnqueue(context.Background(), testBucket, "", "", tasks, false); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	close(tasks)
+
+	var keys []string
+	for task := range tasks {
+		keys = append(keys, task.Key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestEnqueueOddKeys(t *testing.T) {
+	client := storage.NewMemoryClient(testBucket)
+	all := make(map[string]bool)
+	for _, tt := range oddKeyTests {
+		if _, err := client.PutObject(context.Background(), testBucket, tt.key, bytes.NewReader([]byte("data")), 4, storage.PutOptions{}); err != nil {
+			t.Fatalf("put %q: %v", tt.key, err)
+		}
+		all[tt.key] = tt.odd
+	}
+
+	var want, wantAll []string
+	for key, odd := range all {
+		if !odd {
+			want = append(want, key)
+		}
+		wantAll = append(wantAll, key)
+	}
+	sort.Strings(want)
+	sort.Strings(wantAll)
+
+	t.Run("default", func(t *testing.T) {
+		got := listTasks(t, &ObjectLister{client: client, logger: zap.NewNop()})
+		assertKeys(t, got, want)
+	})
+	t.Run("allow weird keys", func(t *testing.T) {
+		got := listTasks(t, &ObjectLister{client: client, logger: zap.NewNop(), allowOddKeys: true})
+		assertKeys(t, got, wantAll)
+	})
+}
+
+func assertKeys(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("enqueued %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("enqueued %q, want %q", got, want)
+		}
+	}
+}
+
+// keyOrderClient hides the layout listing of the memory client, like an S3
+// source that only lists in key order
+type keyOrderClient struct {
+	storage.Client
+}
+
+func TestReadOrderLayout(t *testing.T) {
+	client := storage.NewMemoryClient(testBucket)
+	// Written out of key order, so that layout and key order differ
+	written := []string{"c/3", "a/1", "d/4", "b/2"}
+	for _, key := range written {
+		if _, err := client.PutObject(context.Background(), testBucket, key, bytes.NewReader([]byte("data")), 4, storage.PutOptions{}); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+	keyOrder := []string{"a/1", "b/2", "c/3", "d/4"}
+
+	tests := []struct {
+		name        string
+		client      storage.Client
+		layoutOrder bool
+		want        []string
+	}{
+		{name: "layout", client: client, layoutOrder: true, want: written},
+		{name: "key", client: client, want: keyOrder},
+		{name: "layout unsupported", client: keyOrderClient{client}, layoutOrder: true, want: keyOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lister := &ObjectLister{client: tt.client, logger: zap.NewNop(), layoutOrder: tt.layoutOrder}
+			tasks := make(chan worker.Task, len(written))
+			if err := lister.ListAndEnqueue(context.Background(), testBucket, "", "", tasks, false); err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			close(tasks)
+
+			var got []string
+			for task := range tasks {
+				got = append(got, task.Key)
+			}
+			assertKeys(t, got, tt.want)
+		})
+	}
+}
+
+// metadataListClient returns user metadata in its listings, as sources that
+// include it in listings do
+type metadataListClient struct {
+	*storage.MemoryClient
+}
+
+func (c metadataListClient) ListObjects(ctx context.Context, bucket, prefix string, opts storage.ListOptions) (<-chan storage.ObjectInfo, <-chan error) {
+	objCh, errCh := c.MemoryClient.ListObjects(ctx, bucket, prefix, opts)
+	out := make(chan storage.ObjectInfo)
+	go func() {
+		defer close(out)
+		for obj := range objCh {
+			out <- c.withMetadata(ctx, bucket, obj)
+		}
+	}()
+	return out, errCh
+}
+
+func (c metadataListClient) ListObjectsPage(ctx context.Context, bucket, prefix, token string) (storage.ListPage, error) {
+	page, err := c.MemoryClient.ListObjectsPage(ctx, bucket, prefix, token)
+	for i, obj := range page.Objects {
+		page.Objects[i] = c.withMetadata(ctx, bucket, obj)
+	}
+	return page, err
+}
+
+func (c metadataListClient) withMetadata(ctx context.Context, bucket string, obj storage.ObjectInfo) storage.ObjectInfo {
+	if info, err := c.HeadObject(ctx, bucket, obj.Key); err == nil {
+		obj.Metadata = info.Metadata
+	}
+	return obj
+}
+
+// TestEnqueueMetadata checks that listed user metadata is carried on the
+// enqueued tasks, both when listing in one pass and page by page
+func TestEnqueueMetadata(t *testing.T) {
+	client := metadataListClient{storage.NewMemoryClient(testBucket)}
+	metadata := map[string]string{"owner": "alice", "project": "archive"}
+	if _, err := client.PutObject(context.Background(), testBucket, "dir/object", bytes.NewReader([]byte("data")), 4, storage.PutOptions{Metadata: metadata}); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		lister *ObjectLister
+	}{
+		{name: "objects", lister: &ObjectLister{client: client, logger: zap.NewNop()}},
+		{name: "pages", lister: &ObjectLister{client: client, logger: zap.NewNop(), listState: newListState(t)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := make(chan worker.Task, 1)
+			if err := tt.lister.ListAndEnqueue(context.Background(), testBucket, "", "", tasks, false); err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			close(tasks)
+
+			task, ok := <-tasks
+			if !ok {
+				t.Fatal("no task enqueued")
+			}
+			if !reflect.DeepEqual(task.Metadata, metadata) {
+				t.Fatalf("task metadata %v, want %v", task.Metadata, metadata)
+			}
+		})
+	}
+}
+
+// newListState opens a checkpoint for page-by-page listing in a temporary
+// directory
+func newListState(t *testing.T) checkpoint.Store {
+	t.Helper()
+	store, err := checkpoint.NewSQLiteStore(filepath.Join(t.TempDir(), "checkpoint.db"), checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("open checkpoint: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/progress"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// memoryWarnFraction is the share of available memory that part buffers
+	// may use before a warning is emitted
+	memoryWarnFraction = 0.5
+
+	// memoryLimitFraction is the share of available memory set as the Go
+	// runtime's soft memory limit in low-memory mode
+	memoryLimitFraction = 0.75
+)
+
+// estimateBufferMemory returns the worst-case memory held by in-memory part
+// buffers when every worker uploads a multipart object at the same time
+func estimateBufferMemory(cfg *config.Config) uint64 {
+	if cfg.Migration.NoMultipart || cfg.Migration.StreamParts {
+		return 0
+	}
+
+	perPart := cfg.Migration.PartSize
+	if cfg.Migration.SpillDir != "" && perPart > cfg.Migration.SpillThreshold {
+		// Larger parts are spilled to disk instead of memory
+		perPart = 0
+	}
+	// Each worker holds a buffer for every part it uploads at once
+	return uint64(perPart) * uint64(cfg.Migration.Concurrency) * uint64(cfg.Migration.PartConcurrency)
+}
+
+// checkBufferMemory warns, or fails with strict, when the worst-case part
+// buffer memory exceeds memoryWarnFraction of the memory available to the process
+func checkBufferMemory(cfg *config.Config, logger *zap.Logger) error {
+	available, ok := availableMemory()
+	if !ok {
+		logger.Debug("Could not detect available memory, skipping buffer memory check")
+		return nil
+	}
+
+	estimate := estimateBufferMemory(cfg)
+	if float64(estimate) <= float64(available)*memoryWarnFraction {
+		return nil
+	}
+
+	msg := fmt.Sprintf("part buffers may use up to %s (part size %s x concurrency %d x part concurrency %d), more than %.0f%% of the %s of available memory; lower --part-size, --concurrency or --part-concurrency, or set --spill-dir",
+		progress.FormatBytes(int64(estimate)), progress.FormatBytes(cfg.Migration.PartSize), cfg.Migration.Concurrency, cfg.Migration.PartConcurrency,
+		memoryWarnFraction*100, progress.FormatBytes(int64(available)))
+	if cfg.Migration.Strict {
+		return fmt.Errorf("%s", msg)
+	}
+	logger.Warn("!!! RISK OF RUNNING OUT OF MEMORY: " + msg)
+	return nil
+}
+
+// applyMemoryLimit sets a soft memory limit for the Go runtime, so that the
+// garbage collector works harder before the container limit is reached. An
+// explicit GOMEMLIMIT takes precedence.
+func applyMemoryLimit(logger *zap.Logger) {
+	if os.Getenv("GOMEMLIMIT") != "" {
+		return
+	}
+	available, ok := availableMemory()
+	if !ok {
+		logger.Debug("Could not detect available memory, not setting a runtime memory limit")
+		return
+	}
+
+	limit := int64(float64(available) * memoryLimitFraction)
+	debug.SetMemoryLimit(limit)
+	logger.Info("Set runtime memory limit for low-memory mode", zap.String("limit", progress.FormatBytes(limit)))
+}
+
+// availableMemory returns the memory available to the process: MemAvailable
+// from /proc/meminfo, capped by the cgroup memory limit when running in a
+// container. It reports false on systems where neither can be read.
+func availableMemory() (uint64, bool) {
+	available, ok := memInfoAvailable()
+
+	for _, path := range []string{
+		"/sys/fs/cgroup/memory.max",                   // cgroup v2
+		"/sys/fs/cgroup/memory/memory.limit_in_bytes", // cgroup v1
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			continue // "max" means unlimited
+		}
+		if !ok || limit < available {
+			available, ok = limit, true
+		}
+		break
+	}
+
+	return available, ok
+}
+
+func memInfoAvailable() (uint64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return kb * 1024, true
+	}
+	return 0, false
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"strings"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+// metadataTransform applies --metadata-rules to the user metadata of tasks
+// before they are uploaded
+type metadataTransform struct {
+	rules  []config.MetadataRule
+	logger *zap.Logger
+}
+
+// newMetadataTransform returns nil when there are no rules
+func newMetadataTransform(rules []config.MetadataRule, logger *zap.Logger) *metadataTransform {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &metadataTransform{rules: rules, logger: logger}
+}
+
+// apply edits the metadata of task. The task gets its own copy, since the
+// listed metadata may be shared. A nil transform leaves task unchanged.
+func (t *metadataTransform) apply(task *worker.Task) {
+	if t == nil {
+		return
+	}
+
+	metadata := make(map[string]string, len(task.Metadata)+len(t.rules))
+	for k, v := range task.Metadata {
+		metadata[k] = v
+	}
+
+	changed := false
+	for _, rule := range t.rules {
+		switch rule.Action {
+		case config.MetadataDrop:
+			for k := range metadata {
+				if strings.HasPrefix(metadataName(k), rule.Key) {
+					delete(metadata, k)
+					changed = true
+				}
+			}
+		case config.MetadataRename:
+			for k, v := range metadata {
+				if metadataName(k) == rule.Key {
+					delete(metadata, k)
+					setMetadata(metadata, rule.Value, v)
+					changed = true
+					break
+				}
+			}
+		case config.MetadataAdd:
+			setMetadata(metadata, rule.Key, rule.Value)
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	t.logger.Debug("Transformed object metadata",
+		zap.String("key", task.Key),
+		zap.Any("source_metadata", task.Metadata),
+		zap.Any("metadata", metadata),
+	)
+	task.Metadata = metadata
+}
+
+// metadataName returns a metadata key lowercased and without the x-amz-meta-
+// prefix, as used in rules
+func metadataName(key string) string {
+	return strings.TrimPrefix(strings.ToLower(key), "x-amz-meta-")
+}
+
+// setMetadata sets name to value, replacing any key that differs only in case
+// or prefix
+func setMetadata(metadata map[string]string, name, value string) {
+	for k := range metadata {
+		if metadataName(k) == name {
+			delete(metadata, k)
+		}
+	}
+	metadata[name] = value
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/storage"
+)
+
+// Check is the outcome of a single pre-flight check
+type Check struct {
+	Name    string
+	Err     error
+	Skipped bool // The check does not apply, e.g. bucket checks on an HTTP sink
+}
+
+// CheckConnectivity connects to both endpoints and checks that the source and
+// destination bucket of every job exist, without migrating anything
+func CheckConnectivity(ctx context.Context, cfg *config.Config) ([]Check, error) {
+	srcClient, dstClient, err := newClients(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks []Check
+	for _, job := range cfg.Migration.Jobs {
+		checks = append(checks,
+			checkBucket(ctx, srcClient, "source", cfg.Source.Endpoint, job.Bucket),
+			checkBucket(ctx, dstClient, "destination", cfg.Target.Endpoint, job.DstBucket),
+		)
+	}
+	return checks, nil
+}
+
+func checkBucket(ctx context.Context, client storage.Client, side, endpoint, bucket string) Check {
+	check := Check{Name: fmt.Sprintf("%s bucket %q on %s", side, bucket, endpoint)}
+
+	exists, err := client.BucketExists(ctx, bucket)
+	switch {
+	case errors.Is(err, storage.ErrNotImplemented):
+		check.Skipped = true
+	case err != nil:
+		check.Err = err
+	case !exists:
+		check.Err = fmt.Errorf("bucket does not exist")
+	}
+	return check
+}
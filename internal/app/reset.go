@@ -0,0 +1,51 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/config"
+)
+
+// resettableStore is implemented by the checkpoint backends
+type resettableStore interface {
+	checkpoint.Store
+	Reset() (int64, error)
+	ResetFailed() (int64, error)
+}
+
+// ResetCheckpoint clears the checkpoint database of cfg in place, keeping the
+// file and its settings. With failedOnly, only failed tasks are reset to
+// pending; otherwise every task record is deleted, so that a --resume run
+// migrates everything again. It returns the number of task records affected.
+// It must not run while a migration is using the checkpoint.
+func ResetCheckpoint(cfg *config.Config, failedOnly bool) (int64, error) {
+	// Opening a missing database file would create an empty one
+	if !checkpoint.IsPostgresDSN(cfg.Migration.Checkpoint) {
+		if _, err := os.Stat(cfg.Migration.Checkpoint); errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("checkpoint %s does not exist", cfg.Migration.Checkpoint)
+		}
+	}
+
+	opened, err := openCheckpointStore(cfg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open checkpoint: %w", err)
+	}
+	store := opened.(resettableStore)
+
+	var n int64
+	if failedOnly {
+		n, err = store.ResetFailed()
+	} else {
+		n, err = store.Reset()
+	}
+	if closeErr := store.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset checkpoint: %w", err)
+	}
+	return n, nil
+}
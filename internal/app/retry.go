@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+// RetryFailed migrates again the objects recorded as failed in the checkpoint
+// instead of listing the source. Only the configured jobs are retried, further
+// limited to the source bucket and key prefix when they are not empty.
+func (m *Migrator) RetryFailed(ctx context.Context, bucket, prefix string) error {
+	if m.cfg.Migration.Object != "" || m.ranges != nil || m.objectKeys != nil {
+		return fmt.Errorf("retry-failed cannot be combined with object, object-list or range-manifest; rerun them with --resume instead")
+	}
+
+	m.logger.Info("Retrying failed tasks from checkpoint",
+		zap.String("checkpoint", checkpoint.DisplayLocation(m.cfg.Migration.Checkpoint)),
+		zap.String("bucket", bucket),
+		zap.String("prefix", prefix),
+		zap.Bool("dry_run", m.cfg.Migration.DryRun),
+	)
+
+	tasks := make(chan worker.Task, m.cfg.Migration.TaskQueueDepth())
+	var wg sync.WaitGroup
+	m.workers.Start(ctx, tasks, &wg)
+
+	err := m.enqueueFailed(ctx, bucket, prefix, tasks)
+	close(tasks)
+	if err != nil {
+		// Let in-flight tasks record their outcome before the checkpoint is closed
+		m.waitForWorkers(&wg)
+		return err
+	}
+
+	wg.Wait()
+	m.workers.Flush(ctx)
+
+	status := m.metrics.GetProgressTracker().GetStatus()
+	m.logger.Info("Retry completed",
+		zap.Int64("success", status.SuccessObjects),
+		zap.Int64("skipped", status.SkippedObjects),
+		zap.Int64("failed", status.FailedObjects),
+	)
+	m.notifyCompletion(ctx)
+	return nil
+}
+
+// enqueueFailed enqueues the failed tasks of every job selected by bucket and
+// prefix. Each object is looked up on the source again, since the checkpoint
+// does not record its content type and metadata.
+func (m *Migrator) enqueueFailed(ctx context.Context, bucket, prefix string, tasks chan<- worker.Task) error {
+	matched := false
+	for _, job := range m.jobs {
+		if bucket != "" && job.Bucket != bucket {
+			continue
+		}
+		filter, ok := narrowPrefix(job.Prefix, prefix)
+		if !ok {
+			continue
+		}
+		matched = true
+
+		records, err := m.checkpoint.ListFailedTasksByBucket(job.Bucket, filter)
+		if err != nil {
+			return fmt.Errorf("failed to list failed tasks: %w", err)
+		}
+		m.logger.Info("Found failed tasks",
+			zap.String("bucket", job.Bucket),
+			zap.String("prefix", filter),
+			zap.Int("tasks", len(records)),
+		)
+
+		lister := m.newLister(job, time.Time{})
+		for _, record := range records {
+			err := lister.enqueueSingleObject(ctx, job.Bucket, record.Key, tasks, m.cfg.Migration.DryRun)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				m.logger.Warn("Cannot retry failed task", zap.String("key", record.Key), zap.Error(err))
+			}
+		}
+	}
+
+	if !matched {
+		return fmt.Errorf("no configured job covers bucket %q and prefix %q", bucket, prefix)
+	}
+	return nil
+}
+
+// narrowPrefix returns the longer of the job prefix and the requested prefix
+// when one contains the other, and false when they select disjoint keys
+func narrowPrefix(jobPrefix, prefix string) (string, bool) {
+	switch {
+	case strings.HasPrefix(prefix, jobPrefix):
+		return prefix, true
+	case strings.HasPrefix(jobPrefix, prefix):
+		return jobPrefix, true
+	}
+	return "", false
+}
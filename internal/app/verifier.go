@@ -0,0 +1,246 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"minio2rustfs/internal/config"
+	"minio2rustfs/internal/storage"
+	"minio2rustfs/internal/worker"
+
+	"go.uber.org/zap"
+)
+
+// Verifier checks that source objects exist on the destination with matching size/etag
+type Verifier struct {
+	cfg       *config.Config
+	logger    *zap.Logger
+	srcClient storage.Client
+	dstClient storage.Client
+	jobs      []migrationJob
+}
+
+// VerifyResult summarizes a verification run
+type VerifyResult struct {
+	Listed     int64 // Objects listed on the source; equals Checked unless sampling
+	Checked    int64
+	Matched    int64
+	Missing    int64
+	Mismatched int64
+	Errors     int64
+}
+
+// Failed returns the number of objects that did not verify
+func (r *VerifyResult) Failed() int64 {
+	return r.Missing + r.Mismatched + r.Errors
+}
+
+// NewVerifier creates a new verifier instance
+func NewVerifier(cfg *config.Config, logger *zap.Logger) (*Verifier, error) {
+	logConfigWarnings(cfg, logger)
+
+	srcClient, dstClient, err := newClients(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := newJobs(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		cfg:       cfg,
+		logger:    logger,
+		srcClient: srcClient,
+		dstClient: dstClient,
+		jobs:      jobs,
+	}, nil
+}
+
+// Run lists the source and verifies every object against the destination
+// using the same channel-fed worker pattern as migration.
+func (v *Verifier) Run(ctx context.Context) (*VerifyResult, error) {
+	v.logger.Info("Starting verification",
+		zap.Any("jobs", v.cfg.Migration.Jobs),
+		zap.String("object", v.cfg.Migration.Object),
+		zap.Int("concurrency", v.cfg.Migration.Concurrency),
+		zap.Float64("sample_rate", v.cfg.Verify.SampleRate),
+		zap.Int64("sample_seed", v.cfg.Verify.SampleSeed),
+	)
+
+	tasks := make(chan worker.Task, v.cfg.Migration.TaskQueueDepth())
+	result := &VerifyResult{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < v.cfg.Migration.Concurrency; i++ {
+		wg.Add(1)
+		go v.worker(ctx, tasks, result, &wg)
+	}
+
+	var err error
+	for _, job := range v.jobs {
+		lister := &ObjectLister{
+			client:       v.srcClient,
+			logger:       v.logger,
+			keys:         job.keys,
+			listOpts:     storage.ListOptions{NonRecursive: v.cfg.Migration.NonRecursive},
+			allowOddKeys: v.cfg.Migration.AllowWeirdKeys,
+		}
+		if err = lister.ListAndEnqueue(ctx, job.Bucket, job.Prefix, v.cfg.Migration.Object, tasks, false); err != nil {
+			break
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	if err != nil {
+		return result, fmt.Errorf("failed to list objects: %w", err)
+	}
+
+	v.logger.Info("Verification completed",
+		zap.Int64("listed", result.Listed),
+		zap.Int64("checked", result.Checked),
+		zap.Int64("matched", result.Matched),
+		zap.Int64("missing", result.Missing),
+		zap.Int64("mismatched", result.Mismatched),
+		zap.Int64("errors", result.Errors),
+	)
+
+	return result, nil
+}
+
+func (v *Verifier) worker(ctx context.Context, tasks <-chan worker.Task, result *VerifyResult, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	for {
+		select {
+		case task, ok := <-tasks:
+			if !ok {
+				return
+			}
+			atomic.AddInt64(&result.Listed, 1)
+			if !v.sampled(task.Key) {
+				continue
+			}
+			v.verifyObject(ctx, task, result)
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (v *Verifier) verifyObject(ctx context.Context, task worker.Task, result *VerifyResult) {
+	atomic.AddInt64(&result.Checked, 1)
+
+	info, err := v.dstClient.HeadObject(ctx, task.DestinationBucket(), task.DestinationKey())
+	if err != nil {
+		if storage.IsNotFound(err) {
+			atomic.AddInt64(&result.Missing, 1)
+			v.logger.Warn("Object missing on destination", zap.String("key", task.Key))
+			return
+		}
+		atomic.AddInt64(&result.Errors, 1)
+		v.logger.Error("Failed to check destination object", zap.String("key", task.Key), zap.Error(err))
+		return
+	}
+
+	if info.Size != task.Size || !etagsMatch(task.ETag, info.ETag) {
+		atomic.AddInt64(&result.Mismatched, 1)
+		v.logger.Warn("Object mismatch on destination",
+			zap.String("key", task.Key),
+			zap.Int64("src_size", task.Size),
+			zap.Int64("dst_size", info.Size),
+			zap.String("src_etag", task.ETag),
+			zap.String("dst_etag", info.ETag),
+		)
+		return
+	}
+
+	// Sampled objects are fully verified by comparing content hashes
+	if v.sampling() {
+		if err := v.compareContent(ctx, task); err != nil {
+			atomic.AddInt64(&result.Mismatched, 1)
+			v.logger.Warn("Object content mismatch on destination",
+				zap.String("key", task.Key),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
+	atomic.AddInt64(&result.Matched, 1)
+}
+
+// sampling reports whether only a sample of objects is verified
+func (v *Verifier) sampling() bool {
+	return v.cfg.Verify.SampleRate < 1
+}
+
+// sampled deterministically selects keys for verification: the key is hashed
+// together with the seed, so the same seed always picks the same objects.
+func (v *Verifier) sampled(key string) bool {
+	if !v.sampling() {
+		return true
+	}
+
+	h := fnv.New64a()
+	var seed [8]byte
+	binary.BigEndian.PutUint64(seed[:], uint64(v.cfg.Verify.SampleSeed))
+	h.Write(seed[:])
+	h.Write([]byte(key))
+
+	return float64(h.Sum64()) < v.cfg.Verify.SampleRate*math.MaxUint64
+}
+
+// compareContent streams the object from both sides and compares SHA-256 digests
+func (v *Verifier) compareContent(ctx context.Context, task worker.Task) error {
+	srcSum, err := objectDigest(ctx, v.srcClient, task.Bucket, task.Key)
+	if err != nil {
+		return fmt.Errorf("failed to read source object: %w", err)
+	}
+
+	dstSum, err := objectDigest(ctx, v.dstClient, task.DestinationBucket(), task.DestinationKey())
+	if err != nil {
+		return fmt.Errorf("failed to read destination object: %w", err)
+	}
+
+	if !bytes.Equal(srcSum, dstSum) {
+		return fmt.Errorf("content digest differs (src %x, dst %x)", srcSum, dstSum)
+	}
+	return nil
+}
+
+func objectDigest(ctx context.Context, client storage.Client, bucket, key string) ([]byte, error) {
+	obj, err := client.GetObject(ctx, bucket, key, storage.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// etagsMatch compares etags, ignoring multipart etags ("<hash>-<parts>")
+// since those depend on the part size used for the upload.
+func etagsMatch(src, dst string) bool {
+	src, dst = strings.Trim(src, `"`), strings.Trim(dst, `"`)
+	if strings.Contains(src, "-") || strings.Contains(dst, "-") {
+		return true
+	}
+	return src == dst
+}
@@ -0,0 +1,81 @@
+package checkpoint
+
+// NoopStore is a Store that keeps nothing. It lets a one-shot run without
+// resume continue when the checkpoint database cannot be opened; tasks are
+// still migrated, but nothing is recorded for a later resume.
+type NoopStore struct{}
+
+// NewNoopStore creates a store that discards all records
+func NewNoopStore() *NoopStore {
+	return &NoopStore{}
+}
+
+// GetTask never finds a task
+func (s *NoopStore) GetTask(bucket, key string) (*TaskRecord, error) {
+	return nil, nil
+}
+
+// SaveTask discards record
+func (s *NoopStore) SaveTask(record *TaskRecord) error {
+	return nil
+}
+
+// SaveTasks discards records
+func (s *NoopStore) SaveTasks(records []*TaskRecord) error {
+	return nil
+}
+
+// ListPendingTasks returns no tasks
+func (s *NoopStore) ListPendingTasks() ([]*TaskRecord, error) {
+	return nil, nil
+}
+
+// ListFailedTasks returns no tasks
+func (s *NoopStore) ListFailedTasks() ([]*TaskRecord, error) {
+	return nil, nil
+}
+
+// ListFailedTasksByBucket returns no tasks
+func (s *NoopStore) ListFailedTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return nil, nil
+}
+
+// GetScanTotals never finds cached totals
+func (s *NoopStore) GetScanTotals(bucket, prefix string) (*ScanTotals, error) {
+	return nil, nil
+}
+
+// SaveScanTotals discards totals
+func (s *NoopStore) SaveScanTotals(totals *ScanTotals) error {
+	return nil
+}
+
+// GetProgress never finds saved progress
+func (s *NoopStore) GetProgress(bucket, prefix string) (*ProgressState, error) {
+	return nil, nil
+}
+
+// SaveProgress discards state
+func (s *NoopStore) SaveProgress(state *ProgressState) error {
+	return nil
+}
+
+// GetListToken never finds a token
+func (s *NoopStore) GetListToken(bucket, prefix string) (string, error) {
+	return "", nil
+}
+
+// SaveListPage discards the page
+func (s *NoopStore) SaveListPage(bucket, prefix, token string, records []*TaskRecord) error {
+	return nil
+}
+
+// ListPendingTasksByBucket returns no tasks
+func (s *NoopStore) ListPendingTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return nil, nil
+}
+
+// Close does nothing
+func (s *NoopStore) Close() error {
+	return nil
+}
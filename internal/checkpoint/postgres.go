@@ -0,0 +1,616 @@
+package checkpoint
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IsPostgresDSN reports whether a checkpoint location is a PostgreSQL
+// connection URL (postgres:// or postgresql://) rather than a SQLite file
+func IsPostgresDSN(location string) bool {
+	return strings.HasPrefix(location, "postgres://") || strings.HasPrefix(location, "postgresql://")
+}
+
+// DisplayLocation returns a checkpoint location safe for logs, with the
+// password of a PostgreSQL URL masked
+func DisplayLocation(location string) string {
+	if !IsPostgresDSN(location) {
+		return location
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "postgres://***"
+	}
+	return u.Redacted()
+}
+
+// PostgresStore implements Store using PostgreSQL, so that several migration
+// hosts can share one checkpoint. PostgreSQL handles concurrent writers
+// itself, so unlike SQLiteStore writes are not serialized; transactions that
+// conflict with each other are retried.
+type PostgresStore struct {
+	db *sql.DB
+
+	closeMu sync.RWMutex // Guards closed and registering pending writes
+	closed  bool
+	writes  sync.WaitGroup // Pending writes that Close waits for
+}
+
+// NewPostgresStore connects to the database at dsn and creates the checkpoint
+// tables if they do not exist
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(50)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(10 * time.Minute)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	store := &PostgresStore{
+		db: db,
+	}
+	if err := store.createTables(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create tables: %w", err)
+	}
+
+	return store, nil
+}
+
+func (s *PostgresStore) createTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS tasks (
+		bucket TEXT NOT NULL,
+		key TEXT NOT NULL,
+		size BIGINT NOT NULL,
+		etag TEXT NOT NULL,
+		dst_etag TEXT,
+		status TEXT NOT NULL,
+		attempts INTEGER DEFAULT 0,
+		last_error TEXT,
+		updated_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (bucket, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+	CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at);
+
+	CREATE TABLE IF NOT EXISTS scan_totals (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		objects BIGINT NOT NULL,
+		bytes BIGINT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
+
+	CREATE TABLE IF NOT EXISTS progress_state (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		processed_objects BIGINT NOT NULL,
+		processed_bytes BIGINT NOT NULL,
+		transferred_bytes BIGINT NOT NULL DEFAULT 0,
+		skipped_bytes BIGINT NOT NULL DEFAULT 0,
+		success_objects BIGINT NOT NULL,
+		skipped_objects BIGINT NOT NULL,
+		elapsed_ms BIGINT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
+
+	CREATE TABLE IF NOT EXISTS list_tokens (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		token TEXT NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
+	`
+
+	// Hosts starting together may race to create the tables
+	return s.retryOnConflict(func() error {
+		_, err := s.db.Exec(query)
+		return err
+	})
+}
+
+// GetTask retrieves a task record, or nil if none
+func (s *PostgresStore) GetTask(bucket, key string) (*TaskRecord, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	query := `
+	SELECT bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at
+	FROM tasks WHERE bucket = $1 AND key = $2
+	`
+
+	record, err := scanTask(s.db.QueryRow(query, bucket, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return record, err
+}
+
+// SaveTask saves or updates a task record
+func (s *PostgresStore) SaveTask(record *TaskRecord) error {
+	return s.SaveTasks([]*TaskRecord{record})
+}
+
+// SaveTasks saves or updates several task records in a single transaction
+func (s *PostgresStore) SaveTasks(records []*TaskRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	// Rows are locked in key order, so that concurrent batches touching the
+	// same tasks wait for each other instead of deadlocking
+	sorted := make([]*TaskRecord, len(records))
+	copy(sorted, records)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Bucket != sorted[j].Bucket {
+			return sorted[i].Bucket < sorted[j].Bucket
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+
+	query := `
+	INSERT INTO tasks
+	(bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (bucket, key) DO UPDATE SET
+		size = excluded.size,
+		etag = excluded.etag,
+		dst_etag = excluded.dst_etag,
+		status = excluded.status,
+		attempts = excluded.attempts,
+		last_error = excluded.last_error,
+		updated_at = excluded.updated_at
+	`
+
+	return s.retryOnConflict(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		now := time.Now()
+		for _, record := range sorted {
+			record.UpdatedAt = now
+			_, err = tx.Exec(query,
+				record.Bucket,
+				record.Key,
+				record.Size,
+				record.ETag,
+				record.DstETag,
+				record.Status,
+				record.Attempts,
+				record.LastError,
+				record.UpdatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to execute insert: %w", err)
+			}
+		}
+
+		return tx.Commit()
+	})
+}
+
+// ListPendingTasks returns all pending tasks
+func (s *PostgresStore) ListPendingTasks() ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusPending, "", "")
+}
+
+// ListFailedTasks returns all failed tasks
+func (s *PostgresStore) ListFailedTasks() ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusFailed, "", "")
+}
+
+// ListPendingTasksByBucket returns the pending tasks of bucket whose key starts with prefix
+func (s *PostgresStore) ListPendingTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusPending, bucket, prefix)
+}
+
+// ListFailedTasksByBucket returns the failed tasks of bucket whose key starts with prefix
+func (s *PostgresStore) ListFailedTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusFailed, bucket, prefix)
+}
+
+// listTasksByStatus returns the tasks with status, limited to bucket when it
+// is not empty and to keys starting with prefix
+func (s *PostgresStore) listTasksByStatus(status TaskStatus, bucket, prefix string) ([]*TaskRecord, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	// As in SQLiteStore the prefix is compared with left rather than LIKE, so
+	// that % and _ in keys need no escaping
+	query := `
+	SELECT bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at
+	FROM tasks WHERE status = $1
+	AND ($2::text = '' OR bucket = $2::text)
+	AND left(key, length($3::text)) = $3::text
+	ORDER BY updated_at ASC
+	`
+
+	rows, err := s.db.Query(query, status, bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*TaskRecord
+	for rows.Next() {
+		record, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// scanTask reads a task record selected with the columns of GetTask
+func scanTask(row interface{ Scan(...any) error }) (*TaskRecord, error) {
+	var record TaskRecord
+	var dstETag, lastError sql.NullString
+
+	err := row.Scan(
+		&record.Bucket,
+		&record.Key,
+		&record.Size,
+		&record.ETag,
+		&dstETag,
+		&record.Status,
+		&record.Attempts,
+		&lastError,
+		&record.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	record.DstETag = dstETag.String
+	record.LastError = lastError.String
+	return &record, nil
+}
+
+// GetScanTotals returns the cached pre-scan totals for a bucket/prefix, or nil if none
+func (s *PostgresStore) GetScanTotals(bucket, prefix string) (*ScanTotals, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	query := `
+	SELECT bucket, prefix, objects, bytes, updated_at
+	FROM scan_totals WHERE bucket = $1 AND prefix = $2
+	`
+
+	var totals ScanTotals
+	err := s.db.QueryRow(query, bucket, prefix).Scan(
+		&totals.Bucket,
+		&totals.Prefix,
+		&totals.Objects,
+		&totals.Bytes,
+		&totals.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
+
+// SaveScanTotals saves or updates the cached pre-scan totals for a bucket/prefix
+func (s *PostgresStore) SaveScanTotals(totals *ScanTotals) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	totals.UpdatedAt = time.Now()
+
+	query := `
+	INSERT INTO scan_totals (bucket, prefix, objects, bytes, updated_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (bucket, prefix) DO UPDATE SET
+		objects = excluded.objects,
+		bytes = excluded.bytes,
+		updated_at = excluded.updated_at
+	`
+
+	return s.retryOnConflict(func() error {
+		_, err := s.db.Exec(query, totals.Bucket, totals.Prefix, totals.Objects, totals.Bytes, totals.UpdatedAt)
+		return err
+	})
+}
+
+// GetProgress retrieves the persisted progress for a bucket/prefix, or nil if none
+func (s *PostgresStore) GetProgress(bucket, prefix string) (*ProgressState, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	query := `
+	SELECT bucket, prefix, processed_objects, processed_bytes, transferred_bytes, skipped_bytes, success_objects, skipped_objects, elapsed_ms, updated_at
+	FROM progress_state WHERE bucket = $1 AND prefix = $2
+	`
+
+	var state ProgressState
+	var elapsedMs int64
+	err := s.db.QueryRow(query, bucket, prefix).Scan(
+		&state.Bucket,
+		&state.Prefix,
+		&state.ProcessedObjects,
+		&state.ProcessedBytes,
+		&state.TransferredBytes,
+		&state.SkippedBytes,
+		&state.SuccessObjects,
+		&state.SkippedObjects,
+		&elapsedMs,
+		&state.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.Elapsed = time.Duration(elapsedMs) * time.Millisecond
+	return &state, nil
+}
+
+// SaveProgress persists the progress for a bucket/prefix
+func (s *PostgresStore) SaveProgress(state *ProgressState) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	state.UpdatedAt = time.Now()
+
+	query := `
+	INSERT INTO progress_state (bucket, prefix, processed_objects, processed_bytes, transferred_bytes, skipped_bytes, success_objects, skipped_objects, elapsed_ms, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	ON CONFLICT (bucket, prefix) DO UPDATE SET
+		processed_objects = excluded.processed_objects,
+		processed_bytes = excluded.processed_bytes,
+		transferred_bytes = excluded.transferred_bytes,
+		skipped_bytes = excluded.skipped_bytes,
+		success_objects = excluded.success_objects,
+		skipped_objects = excluded.skipped_objects,
+		elapsed_ms = excluded.elapsed_ms,
+		updated_at = excluded.updated_at
+	`
+
+	return s.retryOnConflict(func() error {
+		_, err := s.db.Exec(query, state.Bucket, state.Prefix, state.ProcessedObjects, state.ProcessedBytes,
+			state.TransferredBytes, state.SkippedBytes, state.SuccessObjects, state.SkippedObjects, state.Elapsed.Milliseconds(), state.UpdatedAt)
+		return err
+	})
+}
+
+// GetListToken returns the saved continuation token of a bucket/prefix listing, or "" if none
+func (s *PostgresStore) GetListToken(bucket, prefix string) (string, error) {
+	if s.isClosed() {
+		return "", errStoreClosed
+	}
+
+	var token string
+	err := s.db.QueryRow(`SELECT token FROM list_tokens WHERE bucket = $1 AND prefix = $2`, bucket, prefix).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token, err
+}
+
+// SaveListPage inserts records as pending where no record exists yet and
+// saves token, or removes the saved token when it is empty, in one transaction
+func (s *PostgresStore) SaveListPage(bucket, prefix, token string, records []*TaskRecord) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	return s.retryOnConflict(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Records of objects already handled, e.g. completed in an earlier
+		// run or by a worker, are kept as they are
+		query := `
+		INSERT INTO tasks (bucket, key, size, etag, status, attempts, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 0, $6)
+		ON CONFLICT (bucket, key) DO NOTHING
+		`
+		now := time.Now()
+		for _, record := range records {
+			if _, err := tx.Exec(query, record.Bucket, record.Key, record.Size, record.ETag, StatusPending, now); err != nil {
+				return fmt.Errorf("failed to insert pending task: %w", err)
+			}
+		}
+
+		if token == "" {
+			_, err = tx.Exec(`DELETE FROM list_tokens WHERE bucket = $1 AND prefix = $2`, bucket, prefix)
+		} else {
+			_, err = tx.Exec(`
+			INSERT INTO list_tokens (bucket, prefix, token, updated_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (bucket, prefix) DO UPDATE SET
+				token = excluded.token,
+				updated_at = excluded.updated_at
+			`, bucket, prefix, token, now)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save list token: %w", err)
+		}
+
+		return tx.Commit()
+	})
+}
+
+// Reset deletes every task record together with the saved progress and
+// listing continuation tokens, keeping the cached scan totals. It returns the
+// number of task records deleted.
+func (s *PostgresStore) Reset() (int64, error) {
+	return s.execWrite(func(tx *sql.Tx) (int64, error) {
+		result, err := tx.Exec(`DELETE FROM tasks`)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete tasks: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM progress_state`); err != nil {
+			return 0, fmt.Errorf("failed to delete progress: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM list_tokens`); err != nil {
+			return 0, fmt.Errorf("failed to delete list tokens: %w", err)
+		}
+		return result.RowsAffected()
+	})
+}
+
+// ResetFailed marks every failed task pending again, clearing its attempts
+// and last error. It returns the number of tasks reset.
+func (s *PostgresStore) ResetFailed() (int64, error) {
+	return s.execWrite(func(tx *sql.Tx) (int64, error) {
+		result, err := tx.Exec(`
+		UPDATE tasks SET status = $1, attempts = 0, last_error = NULL, updated_at = $2
+		WHERE status = $3
+		`, StatusPending, time.Now(), StatusFailed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reset failed tasks: %w", err)
+		}
+		return result.RowsAffected()
+	})
+}
+
+// execWrite runs fn in a write transaction, retrying on conflicts, and
+// returns the count fn reports
+func (s *PostgresStore) execWrite(fn func(tx *sql.Tx) (int64, error)) (int64, error) {
+	if err := s.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer s.writes.Done()
+
+	var n int64
+	err := s.retryOnConflict(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if n, err = fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return n, err
+}
+
+// retryOnConflict retries operation while it fails with a deadlock or
+// serialization failure, which PostgreSQL resolves by aborting one of the
+// conflicting transactions
+func (s *PostgresStore) retryOnConflict(operation func() error) error {
+	const maxRetries = 10
+	baseDelay := 50 * time.Millisecond
+
+	var err error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		if err = operation(); err == nil || !isPostgresConflict(err) {
+			return err
+		}
+		time.Sleep(baseDelay * time.Duration(1<<uint(attempt)))
+	}
+	return err
+}
+
+// isPostgresConflict reports whether err is a deadlock, a serialization
+// failure or a unique violation from a concurrent CREATE TABLE IF NOT EXISTS
+func isPostgresConflict(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	switch pqErr.Code {
+	case "40001", "40P01", "23505":
+		return true
+	}
+	return false
+}
+
+// beginWrite registers a pending write, failing once Close has been called.
+// The caller must call s.writes.Done when the write finishes.
+func (s *PostgresStore) beginWrite() error {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	if s.closed {
+		return errStoreClosed
+	}
+	s.writes.Add(1)
+	return nil
+}
+
+func (s *PostgresStore) isClosed() bool {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	return s.closed
+}
+
+// Close rejects new operations, waits up to closeFlushTimeout for pending
+// writes to be committed and closes the connection pool. It is safe to call
+// more than once and from any goroutine.
+func (s *PostgresStore) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.closeMu.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		s.writes.Wait()
+		close(flushed)
+	}()
+
+	var flushErr error
+	select {
+	case <-flushed:
+	case <-time.After(closeFlushTimeout):
+		flushErr = fmt.Errorf("timed out after %s waiting for pending checkpoint writes", closeFlushTimeout)
+	}
+
+	return errors.Join(flushErr, s.db.Close())
+}
@@ -0,0 +1,223 @@
+package checkpoint
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// ownerMetadataKey identifies the run that last uploaded the remote checkpoint
+const ownerMetadataKey = "Checkpoint-Owner"
+
+// ErrRemoteConflict is returned when another run has written the remote checkpoint
+var ErrRemoteConflict = errors.New("remote checkpoint was written by another run")
+
+// ErrRemoteExists is returned by Claim when a fresh run finds a remote checkpoint
+var ErrRemoteExists = errors.New("remote checkpoint already exists")
+
+// Snapshotter is implemented by stores that can write a consistent copy of themselves to a file
+type Snapshotter interface {
+	Snapshot(path string) error
+}
+
+// RemoteSync mirrors the local checkpoint database to an object so that
+// migration state survives the loss of the local disk.
+//
+// Every upload is tagged with a per-run owner token. Before uploading, the
+// current remote owner is checked: if it is neither this run nor the run whose
+// checkpoint was downloaded, another runner is writing the same object and the
+// upload is refused instead of clobbering its state. The upload itself is
+// conditional on the ETag that check saw (or on the object not existing), so
+// a run that writes between the check and the upload is detected as well.
+type RemoteSync struct {
+	mu        sync.Mutex
+	client    storage.Client
+	bucket    string
+	key       string
+	localPath string
+	owner     string
+	expected  string // Owner of the remote version downloaded on resume
+	logger    *zap.Logger
+}
+
+// NewRemoteSync creates a remote checkpoint mirror for the database at localPath
+func NewRemoteSync(client storage.Client, bucket, key, localPath string, logger *zap.Logger) (*RemoteSync, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate owner token: %w", err)
+	}
+
+	return &RemoteSync{
+		client:    client,
+		bucket:    bucket,
+		key:       key,
+		localPath: localPath,
+		owner:     hex.EncodeToString(token),
+		logger:    logger,
+	}, nil
+}
+
+// Claim checks that no remote checkpoint exists when starting fresh without
+// downloading the remote state. An existing one may belong to a live run, so
+// it is never taken over; it returns ErrRemoteExists instead.
+func (r *RemoteSync) Claim(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, exists, err := r.remoteInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s/%s (owner %s)", ErrRemoteExists, r.bucket, r.key, info.Metadata[ownerMetadataKey])
+	}
+	return nil
+}
+
+// Download replaces the local checkpoint with the remote one. It returns
+// false if no remote checkpoint exists yet.
+func (r *RemoteSync) Download(ctx context.Context) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, exists, err := r.remoteInfo(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	obj, err := r.client.GetObject(ctx, r.bucket, r.key, storage.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to get remote checkpoint: %w", err)
+	}
+	defer obj.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.localPath), filepath.Base(r.localPath)+".download-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, obj); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("failed to download remote checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+
+	// Stale WAL files from a previous local run must not be replayed onto the downloaded database
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(r.localPath + suffix); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
+	}
+	if err := os.Rename(tmp.Name(), r.localPath); err != nil {
+		return false, fmt.Errorf("failed to replace local checkpoint: %w", err)
+	}
+
+	r.expected = info.Metadata[ownerMetadataKey]
+	return true, nil
+}
+
+// Upload snapshots the store and uploads it, refusing with ErrRemoteConflict
+// if another run has written the remote checkpoint in the meantime.
+func (r *RemoteSync) Upload(ctx context.Context, store Snapshotter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, exists, err := r.remoteInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if owner := info.Metadata[ownerMetadataKey]; exists && owner != r.owner && (r.expected == "" || owner != r.expected) {
+		return fmt.Errorf("%w (owner %s)", ErrRemoteConflict, owner)
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(r.localPath), "checkpoint-snapshot-")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	snapshotPath := filepath.Join(dir, "checkpoint.db")
+	if err := store.Snapshot(snapshotPath); err != nil {
+		return fmt.Errorf("failed to snapshot checkpoint: %w", err)
+	}
+
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	opts := storage.PutOptions{
+		ContentType:      "application/vnd.sqlite3",
+		Metadata:         map[string]string{ownerMetadataKey: r.owner},
+		DisableMultipart: true, // Preconditions only apply to single PUTs
+	}
+	if exists {
+		opts.IfMatch = info.ETag
+	} else {
+		opts.IfNoneMatch = "*"
+	}
+	_, err = r.client.PutObject(ctx, r.bucket, r.key, f, stat.Size(), opts)
+	if storage.IsPreconditionFailed(err) {
+		return fmt.Errorf("%w (changed during upload)", ErrRemoteConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload remote checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Run uploads the checkpoint every interval until ctx is done. It stops
+// early if another run takes over the remote checkpoint.
+func (r *RemoteSync) Run(ctx context.Context, interval time.Duration, store Snapshotter) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := r.Upload(ctx, store)
+			if errors.Is(err, ErrRemoteConflict) {
+				r.logger.Error("Stopping remote checkpoint uploads", zap.Error(err))
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				r.logger.Warn("Failed to upload remote checkpoint", zap.Error(err))
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// remoteInfo returns the remote checkpoint's metadata and whether it exists
+func (r *RemoteSync) remoteInfo(ctx context.Context) (storage.ObjectInfo, bool, error) {
+	info, err := r.client.HeadObject(ctx, r.bucket, r.key)
+	if storage.IsNotFound(err) {
+		return storage.ObjectInfo{}, false, nil
+	}
+	if err != nil {
+		return storage.ObjectInfo{}, false, fmt.Errorf("failed to check remote checkpoint: %w", err)
+	}
+	return info, true, nil
+}
@@ -0,0 +1,118 @@
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const (
+	remoteBucket = "bucket"
+	remoteKey    = "checkpoint.db"
+)
+
+// snapshotData is a Snapshotter writing fixed contents
+type snapshotData []byte
+
+func (s snapshotData) Snapshot(path string) error {
+	return os.WriteFile(path, s, 0o644)
+}
+
+// racingClient runs onHead once, right after the next HEAD of the remote
+// checkpoint, like another run writing between the owner check and the upload
+type racingClient struct {
+	*storage.MemoryClient
+	onHead func()
+}
+
+func (c *racingClient) HeadObject(ctx context.Context, bucket, key string) (storage.ObjectInfo, error) {
+	info, err := c.MemoryClient.HeadObject(ctx, bucket, key)
+	if hook := c.onHead; hook != nil {
+		c.onHead = nil
+		hook()
+	}
+	return info, err
+}
+
+func newTestRemoteSync(t *testing.T, client storage.Client) *RemoteSync {
+	t.Helper()
+	remote, err := NewRemoteSync(client, remoteBucket, remoteKey, filepath.Join(t.TempDir(), "checkpoint.db"), zap.NewNop())
+	if err != nil {
+		t.Fatalf("new remote sync: %v", err)
+	}
+	return remote
+}
+
+func TestRemoteSyncClaimRefusesExisting(t *testing.T) {
+	client := storage.NewMemoryClient(remoteBucket)
+	live := newTestRemoteSync(t, client)
+	if err := live.Claim(context.Background()); err != nil {
+		t.Fatalf("claim absent checkpoint: %v", err)
+	}
+	if err := live.Upload(context.Background(), snapshotData("live 1")); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	fresh := newTestRemoteSync(t, client)
+	if err := fresh.Claim(context.Background()); !errors.Is(err, ErrRemoteExists) {
+		t.Fatalf("claim existing checkpoint: %v, want ErrRemoteExists", err)
+	}
+	if err := fresh.Upload(context.Background(), snapshotData("fresh")); !errors.Is(err, ErrRemoteConflict) {
+		t.Fatalf("upload over live run: %v, want ErrRemoteConflict", err)
+	}
+
+	if err := live.Upload(context.Background(), snapshotData("live 2")); err != nil {
+		t.Fatalf("live run upload after refused claim: %v", err)
+	}
+	if got, _ := client.Data(remoteBucket, remoteKey); !bytes.Equal(got, []byte("live 2")) {
+		t.Fatalf("remote checkpoint %q, want the live run's", got)
+	}
+}
+
+func TestRemoteSyncUploadRace(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{name: "first upload", exists: false},
+		{name: "later upload", exists: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &racingClient{MemoryClient: storage.NewMemoryClient(remoteBucket)}
+			remote := newTestRemoteSync(t, client)
+			if err := remote.Claim(context.Background()); err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+			if tt.exists {
+				if err := remote.Upload(context.Background(), snapshotData("mine 1")); err != nil {
+					t.Fatalf("upload: %v", err)
+				}
+			}
+
+			// The other run writes after this run's owner check passed
+			other := newTestRemoteSync(t, client.MemoryClient)
+			client.onHead = func() {
+				if _, err := other.Download(context.Background()); err != nil {
+					t.Errorf("other download: %v", err)
+				}
+				if err := other.Upload(context.Background(), snapshotData("other")); err != nil {
+					t.Errorf("other upload: %v", err)
+				}
+			}
+			if err := remote.Upload(context.Background(), snapshotData("mine 2")); !errors.Is(err, ErrRemoteConflict) {
+				t.Fatalf("upload racing another run: %v, want ErrRemoteConflict", err)
+			}
+			if got, _ := client.Data(remoteBucket, remoteKey); !bytes.Equal(got, []byte("other")) {
+				t.Fatalf("remote checkpoint %q, want the other run's", got)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package checkpoint
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -10,17 +11,66 @@ import (
 	_ "modernc.org/sqlite"
 )
 
+// closeFlushTimeout bounds how long Close waits for pending writes, so that a
+// shutdown on SIGTERM finishes within a container's termination grace period
+const closeFlushTimeout = 5 * time.Second
+
+// errStoreClosed is returned by operations on a closed store
+var errStoreClosed = errors.New("database store is closed")
+
 // SQLiteStore implements Store using SQLite
 type SQLiteStore struct {
 	db      *sql.DB
-	closed  bool
 	writeMu sync.Mutex
+
+	closeMu sync.RWMutex // Guards closed and registering pending writes
+	closed  bool
+	writes  sync.WaitGroup // Pending writes that Close waits for
+}
+
+// SQLiteOptions tunes SQLite for large checkpoints. Zero values keep the
+// SQLite defaults.
+type SQLiteOptions struct {
+	PageSize  int64 // PRAGMA page_size in bytes; only takes effect when the database is created
+	MmapSize  int64 // PRAGMA mmap_size in bytes
+	CacheSize int64 // PRAGMA cache_size; negative values are KiB, positive values are pages
+
+	MaxOpenConns int // Connections in the pool; 0 keeps the default of 50
+}
+
+// LargeMigrationOptions is a preset for checkpoints with hundreds of millions
+// of tasks: 16 KiB pages, 1 GiB of memory-mapped I/O and a 256 MiB page cache.
+// The values are not benchmarked at that scale; pages stay moderate because
+// every commit rewrites whole pages.
+var LargeMigrationOptions = SQLiteOptions{
+	PageSize:  16384,
+	MmapSize:  1 << 30,
+	CacheSize: -262144,
+}
+
+// pragmas returns the DSN query parameters applying the options on every connection
+func (o SQLiteOptions) pragmas() string {
+	var params []string
+	// page_size must come first so that it applies before the tables are created
+	if o.PageSize > 0 {
+		params = append(params, fmt.Sprintf("_pragma=page_size(%d)", o.PageSize))
+	}
+	if o.MmapSize > 0 {
+		params = append(params, fmt.Sprintf("_pragma=mmap_size(%d)", o.MmapSize))
+	}
+	if o.CacheSize != 0 {
+		params = append(params, fmt.Sprintf("_pragma=cache_size(%d)", o.CacheSize))
+	}
+	return strings.Join(params, "&")
 }
 
 // NewSQLiteStore creates a new SQLite checkpoint store
-func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
+func NewSQLiteStore(dbPath string, opts SQLiteOptions) (*SQLiteStore, error) {
 	// Configure SQLite for concurrent access
 	dsn := fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_cache_size=2000&_foreign_keys=on&_busy_timeout=60000", dbPath)
+	if pragmas := opts.pragmas(); pragmas != "" {
+		dsn += "&" + pragmas
+	}
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -30,10 +80,13 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 	db.SetMaxOpenConns(50)                  // 增加并发连接数
 	db.SetMaxIdleConns(10)                  // 增加空闲连接数
 	db.SetConnMaxLifetime(10 * time.Minute) // 增加连接生命周期
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+		db.SetMaxIdleConns(opts.MaxOpenConns)
+	}
 
 	store := &SQLiteStore{
-		db:     db,
-		closed: false,
+		db: db,
 	}
 	if err := store.createTables(); err != nil {
 		db.Close()
@@ -59,17 +112,79 @@ func (s *SQLiteStore) createTables() error {
 	
 	CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
 	CREATE INDEX IF NOT EXISTS idx_tasks_updated_at ON tasks(updated_at);
+
+	CREATE TABLE IF NOT EXISTS scan_totals (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		objects INTEGER NOT NULL,
+		bytes INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
+
+	CREATE TABLE IF NOT EXISTS progress_state (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		processed_objects INTEGER NOT NULL,
+		processed_bytes INTEGER NOT NULL,
+		success_objects INTEGER NOT NULL,
+		skipped_objects INTEGER NOT NULL,
+		elapsed_ms INTEGER NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
+
+	CREATE TABLE IF NOT EXISTS list_tokens (
+		bucket TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		token TEXT NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (bucket, prefix)
+	);
 	`
 
-	_, err := s.db.Exec(query)
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("tasks", "dst_etag", "TEXT"); err != nil {
+		return err
+	}
+	if err := s.addColumnIfMissing("progress_state", "transferred_bytes", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	return s.addColumnIfMissing("progress_state", "skipped_bytes", "INTEGER NOT NULL DEFAULT 0")
+}
+
+// addColumnIfMissing adds a column to a table created by an older version
+func (s *SQLiteStore) addColumnIfMissing(table, column, definition string) error {
+	rows, err := s.db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
 // GetTask retrieves a task record with retry mechanism
 func (s *SQLiteStore) GetTask(bucket, key string) (*TaskRecord, error) {
 	// Check if store is closed
-	if s.closed {
-		return nil, fmt.Errorf("database store is closed")
+	if s.isClosed() {
+		return nil, errStoreClosed
 	}
 
 	// Check if database is still open
@@ -89,20 +204,21 @@ func (s *SQLiteStore) GetTask(bucket, key string) (*TaskRecord, error) {
 // getTaskInternal performs the actual get operation
 func (s *SQLiteStore) getTaskInternal(bucket, key string) (*TaskRecord, error) {
 	query := `
-	SELECT bucket, key, size, etag, status, attempts, last_error, updated_at
+	SELECT bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at
 	FROM tasks WHERE bucket = ? AND key = ?
 	`
 
 	row := s.db.QueryRow(query, bucket, key)
 
 	var record TaskRecord
-	var lastError sql.NullString
+	var dstETag, lastError sql.NullString
 
 	err := row.Scan(
 		&record.Bucket,
 		&record.Key,
 		&record.Size,
 		&record.ETag,
+		&dstETag,
 		&record.Status,
 		&record.Attempts,
 		&lastError,
@@ -116,6 +232,7 @@ func (s *SQLiteStore) getTaskInternal(bucket, key string) (*TaskRecord, error) {
 		return nil, err
 	}
 
+	record.DstETag = dstETag.String
 	if lastError.Valid {
 		record.LastError = lastError.String
 	}
@@ -125,10 +242,11 @@ func (s *SQLiteStore) getTaskInternal(bucket, key string) (*TaskRecord, error) {
 
 // SaveTask saves or updates a task record with retry mechanism
 func (s *SQLiteStore) SaveTask(record *TaskRecord) error {
-	// Check if store is closed
-	if s.closed {
-		return fmt.Errorf("database store is closed")
+	// Register the write so that Close waits for it
+	if err := s.beginWrite(); err != nil {
+		return err
 	}
+	defer s.writes.Done()
 
 	// Check if database is still open
 	if err := s.db.Ping(); err != nil {
@@ -144,10 +262,32 @@ func (s *SQLiteStore) SaveTask(record *TaskRecord) error {
 	})
 }
 
+// SaveTasks saves or updates several task records in a single transaction
+func (s *SQLiteStore) SaveTasks(records []*TaskRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.retryOnBusy(func() error {
+		return s.saveTasksWithTransaction(records)
+	})
+}
+
 // saveTaskWithTransaction performs the actual save operation in a transaction
 func (s *SQLiteStore) saveTaskWithTransaction(record *TaskRecord) error {
-	record.UpdatedAt = time.Now()
+	return s.saveTasksWithTransaction([]*TaskRecord{record})
+}
 
+// saveTasksWithTransaction upserts records in one transaction
+func (s *SQLiteStore) saveTasksWithTransaction(records []*TaskRecord) error {
 	// Use a transaction for better concurrency
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -158,29 +298,35 @@ func (s *SQLiteStore) saveTaskWithTransaction(record *TaskRecord) error {
 	// Use UPSERT to avoid DELETE+INSERT of REPLACE which increases lock contention
 	query := `
     INSERT INTO tasks 
-    (bucket, key, size, etag, status, attempts, last_error, updated_at)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    (bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
     ON CONFLICT(bucket, key) DO UPDATE SET
         size = excluded.size,
         etag = excluded.etag,
+        dst_etag = excluded.dst_etag,
         status = excluded.status,
         attempts = excluded.attempts,
         last_error = excluded.last_error,
         updated_at = excluded.updated_at
     `
 
-	_, err = tx.Exec(query,
-		record.Bucket,
-		record.Key,
-		record.Size,
-		record.ETag,
-		record.Status,
-		record.Attempts,
-		record.LastError,
-		record.UpdatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to execute insert: %w", err)
+	now := time.Now()
+	for _, record := range records {
+		record.UpdatedAt = now
+		_, err = tx.Exec(query,
+			record.Bucket,
+			record.Key,
+			record.Size,
+			record.ETag,
+			record.DstETag,
+			record.Status,
+			record.Attempts,
+			record.LastError,
+			record.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to execute insert: %w", err)
+		}
 	}
 
 	return tx.Commit()
@@ -229,22 +375,38 @@ func isSQLiteBusyError(err error) bool {
 
 // ListPendingTasks returns all pending tasks
 func (s *SQLiteStore) ListPendingTasks() ([]*TaskRecord, error) {
-	return s.listTasksByStatus(StatusPending)
+	return s.listTasksByStatus(StatusPending, "", "")
 }
 
 // ListFailedTasks returns all failed tasks
 func (s *SQLiteStore) ListFailedTasks() ([]*TaskRecord, error) {
-	return s.listTasksByStatus(StatusFailed)
+	return s.listTasksByStatus(StatusFailed, "", "")
+}
+
+// ListPendingTasksByBucket returns the pending tasks of bucket whose key starts with prefix
+func (s *SQLiteStore) ListPendingTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusPending, bucket, prefix)
 }
 
-func (s *SQLiteStore) listTasksByStatus(status TaskStatus) ([]*TaskRecord, error) {
+// ListFailedTasksByBucket returns the failed tasks of bucket whose key starts with prefix
+func (s *SQLiteStore) ListFailedTasksByBucket(bucket, prefix string) ([]*TaskRecord, error) {
+	return s.listTasksByStatus(StatusFailed, bucket, prefix)
+}
+
+// listTasksByStatus returns the tasks with status, limited to bucket when it
+// is not empty and to keys starting with prefix
+func (s *SQLiteStore) listTasksByStatus(status TaskStatus, bucket, prefix string) ([]*TaskRecord, error) {
+	// The prefix is compared with substr rather than LIKE, so that % and _ in
+	// keys need no escaping
 	query := `
-	SELECT bucket, key, size, etag, status, attempts, last_error, updated_at
+	SELECT bucket, key, size, etag, dst_etag, status, attempts, last_error, updated_at
 	FROM tasks WHERE status = ?
+	AND (? = '' OR bucket = ?)
+	AND substr(key, 1, length(?)) = ?
 	ORDER BY updated_at ASC
 	`
 
-	rows, err := s.db.Query(query, status)
+	rows, err := s.db.Query(query, status, bucket, bucket, prefix, prefix)
 	if err != nil {
 		return nil, err
 	}
@@ -254,13 +416,14 @@ func (s *SQLiteStore) listTasksByStatus(status TaskStatus) ([]*TaskRecord, error
 
 	for rows.Next() {
 		var record TaskRecord
-		var lastError sql.NullString
+		var dstETag, lastError sql.NullString
 
 		err := rows.Scan(
 			&record.Bucket,
 			&record.Key,
 			&record.Size,
 			&record.ETag,
+			&dstETag,
 			&record.Status,
 			&record.Attempts,
 			&lastError,
@@ -270,6 +433,7 @@ func (s *SQLiteStore) listTasksByStatus(status TaskStatus) ([]*TaskRecord, error
 			return nil, err
 		}
 
+		record.DstETag = dstETag.String
 		if lastError.Valid {
 			record.LastError = lastError.String
 		}
@@ -280,8 +444,312 @@ func (s *SQLiteStore) listTasksByStatus(status TaskStatus) ([]*TaskRecord, error
 	return records, rows.Err()
 }
 
-// Close closes the database connection
+// GetScanTotals returns the cached pre-scan totals for a bucket/prefix, or nil if none
+func (s *SQLiteStore) GetScanTotals(bucket, prefix string) (*ScanTotals, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	query := `
+	SELECT bucket, prefix, objects, bytes, updated_at
+	FROM scan_totals WHERE bucket = ? AND prefix = ?
+	`
+
+	var totals ScanTotals
+	err := s.db.QueryRow(query, bucket, prefix).Scan(
+		&totals.Bucket,
+		&totals.Prefix,
+		&totals.Objects,
+		&totals.Bytes,
+		&totals.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &totals, nil
+}
+
+// SaveScanTotals saves or updates the cached pre-scan totals for a bucket/prefix
+func (s *SQLiteStore) SaveScanTotals(totals *ScanTotals) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	totals.UpdatedAt = time.Now()
+
+	query := `
+	INSERT INTO scan_totals (bucket, prefix, objects, bytes, updated_at)
+	VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(bucket, prefix) DO UPDATE SET
+		objects = excluded.objects,
+		bytes = excluded.bytes,
+		updated_at = excluded.updated_at
+	`
+
+	return s.retryOnBusy(func() error {
+		_, err := s.db.Exec(query, totals.Bucket, totals.Prefix, totals.Objects, totals.Bytes, totals.UpdatedAt)
+		return err
+	})
+}
+
+// GetListToken returns the saved continuation token of a bucket/prefix listing, or "" if none
+func (s *SQLiteStore) GetListToken(bucket, prefix string) (string, error) {
+	if s.isClosed() {
+		return "", errStoreClosed
+	}
+
+	var token string
+	err := s.db.QueryRow(`SELECT token FROM list_tokens WHERE bucket = ? AND prefix = ?`, bucket, prefix).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token, err
+}
+
+// SaveListPage inserts records as pending where no record exists yet and
+// saves token, or removes the saved token when it is empty, in one transaction
+func (s *SQLiteStore) SaveListPage(bucket, prefix, token string, records []*TaskRecord) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	return s.retryOnBusy(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Records of objects already handled, e.g. completed in an earlier
+		// run or by a worker, are kept as they are
+		query := `
+		INSERT INTO tasks (bucket, key, size, etag, status, attempts, updated_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?)
+		ON CONFLICT(bucket, key) DO NOTHING
+		`
+		now := time.Now()
+		for _, record := range records {
+			if _, err := tx.Exec(query, record.Bucket, record.Key, record.Size, record.ETag, StatusPending, now); err != nil {
+				return fmt.Errorf("failed to insert pending task: %w", err)
+			}
+		}
+
+		if token == "" {
+			_, err = tx.Exec(`DELETE FROM list_tokens WHERE bucket = ? AND prefix = ?`, bucket, prefix)
+		} else {
+			_, err = tx.Exec(`
+			INSERT INTO list_tokens (bucket, prefix, token, updated_at)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(bucket, prefix) DO UPDATE SET
+				token = excluded.token,
+				updated_at = excluded.updated_at
+			`, bucket, prefix, token, now)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save list token: %w", err)
+		}
+
+		return tx.Commit()
+	})
+}
+
+// GetProgress retrieves the persisted progress for a bucket/prefix, or nil if none
+func (s *SQLiteStore) GetProgress(bucket, prefix string) (*ProgressState, error) {
+	if s.isClosed() {
+		return nil, errStoreClosed
+	}
+
+	query := `
+	SELECT bucket, prefix, processed_objects, processed_bytes, transferred_bytes, skipped_bytes, success_objects, skipped_objects, elapsed_ms, updated_at
+	FROM progress_state WHERE bucket = ? AND prefix = ?
+	`
+
+	var state ProgressState
+	var elapsedMs int64
+	err := s.db.QueryRow(query, bucket, prefix).Scan(
+		&state.Bucket,
+		&state.Prefix,
+		&state.ProcessedObjects,
+		&state.ProcessedBytes,
+		&state.TransferredBytes,
+		&state.SkippedBytes,
+		&state.SuccessObjects,
+		&state.SkippedObjects,
+		&elapsedMs,
+		&state.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	state.Elapsed = time.Duration(elapsedMs) * time.Millisecond
+	return &state, nil
+}
+
+// SaveProgress persists the progress for a bucket/prefix
+func (s *SQLiteStore) SaveProgress(state *ProgressState) error {
+	if err := s.beginWrite(); err != nil {
+		return err
+	}
+	defer s.writes.Done()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	state.UpdatedAt = time.Now()
+
+	query := `
+	INSERT INTO progress_state (bucket, prefix, processed_objects, processed_bytes, transferred_bytes, skipped_bytes, success_objects, skipped_objects, elapsed_ms, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(bucket, prefix) DO UPDATE SET
+		processed_objects = excluded.processed_objects,
+		processed_bytes = excluded.processed_bytes,
+		transferred_bytes = excluded.transferred_bytes,
+		skipped_bytes = excluded.skipped_bytes,
+		success_objects = excluded.success_objects,
+		skipped_objects = excluded.skipped_objects,
+		elapsed_ms = excluded.elapsed_ms,
+		updated_at = excluded.updated_at
+	`
+
+	return s.retryOnBusy(func() error {
+		_, err := s.db.Exec(query, state.Bucket, state.Prefix, state.ProcessedObjects, state.ProcessedBytes,
+			state.TransferredBytes, state.SkippedBytes, state.SuccessObjects, state.SkippedObjects, state.Elapsed.Milliseconds(), state.UpdatedAt)
+		return err
+	})
+}
+
+// Reset deletes every task record together with the saved progress and
+// listing continuation tokens, so that the next run starts over. The database
+// file and its settings are kept, and so are the cached scan totals, which do
+// not depend on earlier runs. It returns the number of task records deleted.
+func (s *SQLiteStore) Reset() (int64, error) {
+	return s.execWrite(func(tx *sql.Tx) (int64, error) {
+		result, err := tx.Exec(`DELETE FROM tasks`)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete tasks: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM progress_state`); err != nil {
+			return 0, fmt.Errorf("failed to delete progress: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM list_tokens`); err != nil {
+			return 0, fmt.Errorf("failed to delete list tokens: %w", err)
+		}
+		return result.RowsAffected()
+	})
+}
+
+// ResetFailed marks every failed task pending again, clearing its attempts
+// and last error. It returns the number of tasks reset.
+func (s *SQLiteStore) ResetFailed() (int64, error) {
+	return s.execWrite(func(tx *sql.Tx) (int64, error) {
+		result, err := tx.Exec(`
+		UPDATE tasks SET status = ?, attempts = 0, last_error = NULL, updated_at = ?
+		WHERE status = ?
+		`, StatusPending, time.Now(), StatusFailed)
+		if err != nil {
+			return 0, fmt.Errorf("failed to reset failed tasks: %w", err)
+		}
+		return result.RowsAffected()
+	})
+}
+
+// execWrite runs fn in a serialized write transaction, retrying while the
+// database is busy, and returns the count fn reports
+func (s *SQLiteStore) execWrite(fn func(tx *sql.Tx) (int64, error)) (int64, error) {
+	if err := s.beginWrite(); err != nil {
+		return 0, err
+	}
+	defer s.writes.Done()
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	var n int64
+	err := s.retryOnBusy(func() error {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if n, err = fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return n, err
+}
+
+// Snapshot writes a consistent copy of the database to path, which must not exist
+func (s *SQLiteStore) Snapshot(path string) error {
+	if s.isClosed() {
+		return errStoreClosed
+	}
+
+	_, err := s.db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// beginWrite registers a pending write, failing once Close has been called.
+// The caller must call s.writes.Done when the write finishes.
+func (s *SQLiteStore) beginWrite() error {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	if s.closed {
+		return errStoreClosed
+	}
+	s.writes.Add(1)
+	return nil
+}
+
+func (s *SQLiteStore) isClosed() bool {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+
+	return s.closed
+}
+
+// Close rejects new operations, waits up to closeFlushTimeout for pending
+// writes to be committed and closes the database. It is safe to call more
+// than once and from any goroutine.
 func (s *SQLiteStore) Close() error {
+	s.closeMu.Lock()
+	if s.closed {
+		s.closeMu.Unlock()
+		return nil
+	}
 	s.closed = true
-	return s.db.Close()
+	s.closeMu.Unlock()
+
+	flushed := make(chan struct{})
+	go func() {
+		s.writes.Wait()
+		close(flushed)
+	}()
+
+	var flushErr error
+	select {
+	case <-flushed:
+	case <-time.After(closeFlushTimeout):
+		flushErr = fmt.Errorf("timed out after %s waiting for pending checkpoint writes", closeFlushTimeout)
+	}
+
+	return errors.Join(flushErr, s.db.Close())
 }
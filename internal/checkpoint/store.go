@@ -12,6 +12,9 @@ const (
 	StatusInProgress TaskStatus = "in_progress"
 	StatusCompleted  TaskStatus = "completed"
 	StatusFailed     TaskStatus = "failed"
+	StatusLocked     TaskStatus = "locked"      // Destination object is locked and cannot be overwritten
+	StatusQuarantine TaskStatus = "quarantined" // Failed in too many runs and no longer attempted
+	StatusUnreadable TaskStatus = "unreadable"  // Source object could not be read and was skipped
 )
 
 // TaskRecord represents a task record in the checkpoint store
@@ -20,19 +23,68 @@ type TaskRecord struct {
 	Key       string     `json:"key"`
 	Size      int64      `json:"size"`
 	ETag      string     `json:"etag"`
+	DstETag   string     `json:"dst_etag,omitempty"` // ETag returned by the destination on upload
 	Status    TaskStatus `json:"status"`
-	Attempts  int        `json:"attempts"`
+	Attempts  int        `json:"attempts"` // Consecutive runs in which the task failed
 	LastError string     `json:"last_error,omitempty"`
 	UpdatedAt time.Time  `json:"updated_at"`
 }
 
+// ScanTotals records the result of a source pre-scan for a bucket/prefix
+type ScanTotals struct {
+	Bucket    string    `json:"bucket"`
+	Prefix    string    `json:"prefix"`
+	Objects   int64     `json:"objects"`
+	Bytes     int64     `json:"bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProgressState records cumulative migration progress for a bucket/prefix so
+// that a resumed run can continue the progress display where it left off
+type ProgressState struct {
+	Bucket           string        `json:"bucket"`
+	Prefix           string        `json:"prefix"`
+	ProcessedObjects int64         `json:"processed_objects"`
+	ProcessedBytes   int64         `json:"processed_bytes"`
+	TransferredBytes int64         `json:"transferred_bytes"`
+	SkippedBytes     int64         `json:"skipped_bytes"`
+	SuccessObjects   int64         `json:"success_objects"`
+	SkippedObjects   int64         `json:"skipped_objects"`
+	Elapsed          time.Duration `json:"elapsed"` // Active migration time across runs
+	UpdatedAt        time.Time     `json:"updated_at"`
+}
+
 // Store defines the interface for checkpoint persistence
 type Store interface {
 	// Task operations
 	GetTask(bucket, key string) (*TaskRecord, error)
 	SaveTask(record *TaskRecord) error
+	SaveTasks(records []*TaskRecord) error // Saves all records in one transaction
 	ListPendingTasks() ([]*TaskRecord, error)
 	ListFailedTasks() ([]*TaskRecord, error)
+	// ListFailedTasksByBucket returns the failed tasks of bucket whose key
+	// starts with prefix
+	ListFailedTasksByBucket(bucket, prefix string) ([]*TaskRecord, error)
+
+	// Scan totals cache
+	GetScanTotals(bucket, prefix string) (*ScanTotals, error)
+	SaveScanTotals(totals *ScanTotals) error
+
+	// Progress persistence
+	GetProgress(bucket, prefix string) (*ProgressState, error)
+	SaveProgress(state *ProgressState) error
+
+	// Listing resume
+	// GetListToken returns the continuation token saved for a bucket/prefix
+	// listing, or "" if none
+	GetListToken(bucket, prefix string) (string, error)
+	// SaveListPage records the objects of a listed page as pending, keeping
+	// existing records, and saves the token continuing after the page in the
+	// same transaction. An empty token marks the listing finished.
+	SaveListPage(bucket, prefix, token string, records []*TaskRecord) error
+	// ListPendingTasksByBucket returns the pending tasks of bucket whose key
+	// starts with prefix
+	ListPendingTasksByBucket(bucket, prefix string) ([]*TaskRecord, error)
 
 	// Cleanup
 	Close() error
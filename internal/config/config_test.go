@@ -0,0 +1,101 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestReverse(t *testing.T) {
+	cfg := &Config{
+		Source: S3Config{Endpoint: "minio:9000", AccessKey: "minio-key"},
+		Target: S3Config{Type: StorageTypeS3, Endpoint: "rustfs:9000", AccessKey: "rustfs-key"},
+		Migration: Migration{
+			Jobs: []JobSpec{{Bucket: "src", DstBucket: "dst"}, {Bucket: "same", DstBucket: "same"}},
+		},
+	}
+	if err := cfg.reverse(); err != nil {
+		t.Fatalf("reverse: %v", err)
+	}
+
+	if cfg.Source.Endpoint != "rustfs:9000" || cfg.Source.AccessKey != "rustfs-key" {
+		t.Errorf("source %+v, want the configured target", cfg.Source)
+	}
+	if cfg.Target.Endpoint != "minio:9000" || cfg.Target.AccessKey != "minio-key" || cfg.Target.Type != StorageTypeS3 {
+		t.Errorf("target %+v, want the configured source as s3", cfg.Target)
+	}
+	want := []JobSpec{{Bucket: "dst", DstBucket: "src"}, {Bucket: "same", DstBucket: "same"}}
+	for i, job := range cfg.Migration.Jobs {
+		if job != want[i] {
+			t.Errorf("job %d: %+v, want %+v", i, job, want[i])
+		}
+	}
+}
+
+func TestReverseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "http target", cfg: Config{Target: S3Config{Type: StorageTypeHTTPSink}}},
+		{name: "destination prefix", cfg: Config{
+			Target:    S3Config{Type: StorageTypeS3},
+			Migration: Migration{Jobs: []JobSpec{{Bucket: "src", DstBucket: "dst", DstPrefix: "backup/"}}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.cfg.reverse(); err == nil {
+				t.Fatalf("reverse succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestResumableListingJobs(t *testing.T) {
+	tests := []struct {
+		name    string
+		jobs    []JobSpec
+		wantErr bool
+	}{
+		{name: "separate buckets", jobs: []JobSpec{{Bucket: "a"}, {Bucket: "b"}}},
+		{name: "separate prefixes", jobs: []JobSpec{{Bucket: "a", Prefix: "logs/"}, {Bucket: "a", Prefix: "photos/"}}},
+		{name: "same prefix", jobs: []JobSpec{{Bucket: "a", Prefix: "logs/", DstBucket: "x"}, {Bucket: "a", Prefix: "logs/", DstBucket: "y"}}, wantErr: true},
+		{name: "nested prefix", jobs: []JobSpec{{Bucket: "a", Prefix: "logs/"}, {Bucket: "a", Prefix: "logs/app/"}}, wantErr: true},
+		{name: "whole bucket", jobs: []JobSpec{{Bucket: "a", Prefix: "logs/"}, {Bucket: "a"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig()
+			cfg.Source = S3Config{Endpoint: "src:9000", AccessKey: "key", SecretKey: "secret"}
+			cfg.Target = S3Config{Type: StorageTypeS3, Endpoint: "dst:9000", AccessKey: "key", SecretKey: "secret"}
+			cfg.Migration.ResumableListing = true
+			cfg.Migration.Jobs = tt.jobs
+			err := cfg.validate()
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "non-overlapping")) {
+				t.Fatalf("validate: %v, want an overlap error", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestResumeFlag(t *testing.T) {
+	for _, args := range [][]string{{"--resume"}, {}} {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.Bool("resume", false, "")
+		if err := flags.Parse(args); err != nil {
+			t.Fatalf("parse %v: %v", args, err)
+		}
+		cfg := defaultConfig()
+		if err := loadFromFlags(cfg, flags); err != nil {
+			t.Fatalf("load %v: %v", args, err)
+		}
+		if want := len(args) > 0; cfg.Migration.Resume != want {
+			t.Errorf("flags %v: resume %v, want %v", args, cfg.Migration.Resume, want)
+		}
+	}
+}
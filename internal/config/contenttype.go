@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseContentTypeMap parses a content-type override map keyed by file
+// extension. The spec is either the path of a file with one "ext=type" entry
+// per line ('#' starts a comment), or inline comma-separated entries such as
+// ".m3u8=application/vnd.apple.mpegurl,.ts=video/mp2t". Extensions are
+// lowercased and given a leading dot.
+func ParseContentTypeMap(spec string) (map[string]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	entries := strings.Split(spec, ",")
+	if data, err := os.ReadFile(spec); err == nil {
+		entries = strings.Split(string(data), "\n")
+	}
+
+	types := make(map[string]string)
+	for _, entry := range entries {
+		if i := strings.IndexByte(entry, '#'); i >= 0 {
+			entry = entry[:i]
+		}
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		ext, contentType, ok := strings.Cut(entry, "=")
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		contentType = strings.TrimSpace(contentType)
+		if !ok || ext == "" || ext == "." || contentType == "" {
+			return nil, fmt.Errorf("invalid content type mapping %q, expected ext=type", entry)
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		types[ext] = contentType
+	}
+
+	return types, nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MetadataAction is the edit a metadata rule makes
+type MetadataAction string
+
+const (
+	MetadataDrop   MetadataAction = "drop"   // Remove every key starting with Key
+	MetadataRename MetadataAction = "rename" // Move the value of Key to Value
+	MetadataAdd    MetadataAction = "add"    // Set Key to the constant Value
+)
+
+// MetadataRule edits the user metadata of migrated objects. Keys are
+// lowercased and without the x-amz-meta- prefix.
+type MetadataRule struct {
+	Action MetadataAction
+	Key    string
+	Value  string // New key for rename, constant value for add
+}
+
+// ParseMetadataRules parses comma-separated metadata rules such as
+// "drop:internal-,rename:owner=team,add:migrated-by=minio2rustfs". Rules are
+// applied in the given order. Keys may be given with or without the
+// x-amz-meta- prefix and are matched case-insensitively.
+func ParseMetadataRules(spec string) ([]MetadataRule, error) {
+	var rules []MetadataRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		action, arg, ok := strings.Cut(entry, ":")
+		rule := MetadataRule{Action: MetadataAction(strings.ToLower(strings.TrimSpace(action)))}
+		switch rule.Action {
+		case MetadataDrop:
+			rule.Key = metadataRuleKey(arg)
+			if !ok || rule.Key == "" {
+				return nil, fmt.Errorf("invalid metadata rule %q, expected drop:<prefix>", entry)
+			}
+		case MetadataRename:
+			from, to, hasTo := strings.Cut(arg, "=")
+			rule.Key, rule.Value = metadataRuleKey(from), metadataRuleKey(to)
+			if !ok || !hasTo || rule.Key == "" || rule.Value == "" {
+				return nil, fmt.Errorf("invalid metadata rule %q, expected rename:<key>=<new key>", entry)
+			}
+		case MetadataAdd:
+			key, value, hasValue := strings.Cut(arg, "=")
+			rule.Key, rule.Value = metadataRuleKey(key), strings.TrimSpace(value)
+			if !ok || !hasValue || rule.Key == "" {
+				return nil, fmt.Errorf("invalid metadata rule %q, expected add:<key>=<value>", entry)
+			}
+		default:
+			return nil, fmt.Errorf("invalid metadata rule %q, expected drop, rename or add", entry)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// metadataRuleKey normalizes a metadata key given in a rule
+func metadataRuleKey(key string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(key)), "x-amz-meta-")
+}
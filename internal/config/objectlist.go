@@ -0,0 +1,40 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseObjectList reads a file with one source object key per line, as
+// written by --failed-manifest. Every non-empty line is a key taken byte for
+// byte, so keys may contain spaces or start with '#'; only a trailing "\r" is
+// removed. Duplicate keys are listed once.
+func ParseObjectList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object list: %w", err)
+	}
+	defer f.Close()
+
+	var keys []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key := strings.TrimSuffix(scanner.Text(), "\r")
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read object list: %w", err)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("object list %s has no keys", path)
+	}
+	return keys, nil
+}
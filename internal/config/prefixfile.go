@@ -0,0 +1,51 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ParsePrefixFile reads a file with one source prefix per line. Blank lines
+// and lines starting with '#' are skipped; surrounding whitespace is trimmed.
+// Prefixes must not overlap, since each one becomes its own job and an object
+// under two of them would be migrated twice.
+func ParsePrefixFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prefix file: %w", err)
+	}
+	defer f.Close()
+
+	var prefixes []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		prefix := strings.TrimSpace(scanner.Text())
+		if prefix == "" || strings.HasPrefix(prefix, "#") || seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		prefixes = append(prefixes, prefix)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read prefix file: %w", err)
+	}
+
+	if len(prefixes) == 0 {
+		return nil, fmt.Errorf("prefix file %s has no prefixes", path)
+	}
+
+	// In sorted order a prefix of another prefix is always followed directly
+	// by a key it contains
+	sorted := append([]string(nil), prefixes...)
+	sort.Strings(sorted)
+	for i := 1; i < len(sorted); i++ {
+		if strings.HasPrefix(sorted[i], sorted[i-1]) {
+			return nil, fmt.Errorf("prefix file %s: prefix %q overlaps %q", path, sorted[i], sorted[i-1])
+		}
+	}
+	return prefixes, nil
+}
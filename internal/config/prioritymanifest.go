@@ -0,0 +1,58 @@
+package config
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParsePriorityManifest reads a CSV manifest with one "key,priority" entry per
+// line and returns the priorities keyed by source key. Higher priorities are
+// migrated first. Lines starting with '#' are comments; keys containing
+// commas can be quoted.
+func ParsePriorityManifest(path string) (map[string]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open priority manifest: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	priorities := make(map[string]int)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read priority manifest: %w", err)
+		}
+
+		line, _ := r.FieldPos(0)
+		if len(record) != 2 {
+			return nil, fmt.Errorf("priority manifest line %d: expected key,priority", line)
+		}
+		if record[0] == "" {
+			return nil, fmt.Errorf("priority manifest line %d: key is empty", line)
+		}
+		priority, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("priority manifest line %d: invalid priority %q", line, record[1])
+		}
+
+		priorities[record[0]] = priority
+	}
+
+	if len(priorities) == 0 {
+		return nil, fmt.Errorf("priority manifest %s has no entries", path)
+	}
+	return priorities, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// RangeEntry selects a byte range of a source object to migrate as a separate
+// destination object
+type RangeEntry struct {
+	Key    string
+	Offset int64
+	Length int64  // 0 migrates up to the end of the object
+	DstKey string // Empty derives "<key>.<first>-<last>" from the range
+}
+
+// ParseRangeManifest reads a CSV manifest with one "key,offset,length[,dst_key]"
+// entry per line. Lines starting with '#' are comments; keys containing commas
+// can be quoted.
+func ParseRangeManifest(path string) ([]RangeEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open range manifest: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	var entries []RangeEntry
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read range manifest: %w", err)
+		}
+
+		line, _ := r.FieldPos(0)
+		if len(record) != 3 && len(record) != 4 {
+			return nil, fmt.Errorf("range manifest line %d: expected key,offset,length[,dst_key]", line)
+		}
+
+		entry := RangeEntry{Key: record[0]}
+		if entry.Key == "" {
+			return nil, fmt.Errorf("range manifest line %d: key is empty", line)
+		}
+		entry.Offset, err = strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+		if err != nil || entry.Offset < 0 {
+			return nil, fmt.Errorf("range manifest line %d: invalid offset %q", line, record[1])
+		}
+		entry.Length, err = strconv.ParseInt(strings.TrimSpace(record[2]), 10, 64)
+		if err != nil || entry.Length < 0 {
+			return nil, fmt.Errorf("range manifest line %d: invalid length %q", line, record[2])
+		}
+		if len(record) == 4 {
+			entry.DstKey = strings.TrimSpace(record[3])
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("range manifest %s has no entries", path)
+	}
+	return entries, nil
+}
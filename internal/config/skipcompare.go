@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SkipCompare lists the attributes that must match for an existing destination
+// object to be considered already migrated
+type SkipCompare struct {
+	ETag     bool
+	Size     bool
+	Metadata []string // User metadata keys, lowercased and without the x-amz-meta- prefix
+}
+
+// ParseSkipCompare parses a comma-separated attribute list such as
+// "etag,size,metadata:sha256". Metadata keys may be given with or without the
+// x-amz-meta- prefix and are matched case-insensitively.
+func ParseSkipCompare(spec string) (SkipCompare, error) {
+	var compare SkipCompare
+	for _, attr := range strings.Split(spec, ",") {
+		attr = strings.TrimSpace(attr)
+		name, key, hasKey := strings.Cut(attr, ":")
+		switch strings.ToLower(name) {
+		case "etag":
+			compare.ETag = true
+		case "size":
+			compare.Size = true
+		case "metadata":
+			key = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(key)), "x-amz-meta-")
+			if !hasKey || key == "" {
+				return SkipCompare{}, fmt.Errorf("invalid skip-compare attribute %q, expected metadata:<key>", attr)
+			}
+			compare.Metadata = append(compare.Metadata, key)
+		case "":
+			continue
+		default:
+			return SkipCompare{}, fmt.Errorf("invalid skip-compare attribute %q, expected etag, size or metadata:<key>", attr)
+		}
+		if hasKey && !strings.EqualFold(name, "metadata") {
+			return SkipCompare{}, fmt.Errorf("invalid skip-compare attribute %q, only metadata takes a key", attr)
+		}
+	}
+
+	if !compare.ETag && !compare.Size && len(compare.Metadata) == 0 {
+		return SkipCompare{}, fmt.Errorf("skip-compare must list at least one attribute")
+	}
+	return compare, nil
+}
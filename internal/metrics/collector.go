@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"sync"
 	"time"
 
 	"minio2rustfs/internal/progress"
@@ -13,10 +16,22 @@ import (
 // Collector collects and exposes metrics
 type Collector struct {
 	objectsTotal    *prometheus.CounterVec
+	failuresTotal   *prometheus.CounterVec
 	bytesTotal      prometheus.Counter
+	bytesSkipped    prometheus.Counter
+	bytesFailed     prometheus.Counter
 	inflightWorkers prometheus.Gauge
 	duration        prometheus.Histogram
+	throttleDelay   prometheus.Gauge
+	listedObjects   prometheus.Gauge
+	listingActive   prometheus.Gauge
+	sourceReads     prometheus.Gauge
+	aclDropped      prometheus.Counter
 	progressTracker *progress.Tracker // Add progress tracker
+
+	serverMu     sync.Mutex
+	server       *http.Server // Metrics HTTP server; nil until StartServer runs
+	serverClosed bool         // Set by Shutdown, so a late StartServer does not serve
 }
 
 // New creates a new metrics collector
@@ -29,10 +44,29 @@ func New() *Collector {
 			},
 			[]string{"status"},
 		),
+		failuresTotal: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "migrate_failures_total",
+				Help: "Total number of failed objects by error category",
+			},
+			[]string{"category"},
+		),
 		bytesTotal: prometheus.NewCounter(
 			prometheus.CounterOpts{
 				Name: "migrate_bytes_total",
-				Help: "Total bytes migrated",
+				Help: "Total bytes transferred to the destination",
+			},
+		),
+		bytesSkipped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "migrate_bytes_skipped_total",
+				Help: "Total bytes of objects skipped because they already exist on the destination",
+			},
+		),
+		bytesFailed: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "migrate_bytes_failed_total",
+				Help: "Total bytes of objects that failed after all retries",
 			},
 		),
 		inflightWorkers: prometheus.NewGauge(
@@ -48,14 +82,52 @@ func New() *Collector {
 				Buckets: prometheus.DefBuckets,
 			},
 		),
+		throttleDelay: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "migrate_throttle_delay_seconds",
+				Help: "Current delay enforced between requests by the auto-throttle (0 when unthrottled)",
+			},
+		),
+		listedObjects: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "migrate_listing_discovered_objects",
+				Help: "Objects discovered so far by the current source listing",
+			},
+		),
+		listingActive: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "migrate_listing_active",
+				Help: "1 while the source is being listed, 0 otherwise",
+			},
+		),
+		sourceReads: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "migrate_source_reads_inflight",
+				Help: "Source reads currently holding a --max-source-reads slot",
+			},
+		),
+		aclDropped: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "migrate_acl_grants_dropped_total",
+				Help: "Source ACL grants that could not be applied to the destination",
+			},
+		),
 		progressTracker: progress.NewTracker(), // Initialize progress tracker
 	}
 
 	// Register metrics
 	prometheus.MustRegister(c.objectsTotal)
+	prometheus.MustRegister(c.failuresTotal)
 	prometheus.MustRegister(c.bytesTotal)
+	prometheus.MustRegister(c.bytesSkipped)
+	prometheus.MustRegister(c.bytesFailed)
 	prometheus.MustRegister(c.inflightWorkers)
 	prometheus.MustRegister(c.duration)
+	prometheus.MustRegister(c.throttleDelay)
+	prometheus.MustRegister(c.listedObjects)
+	prometheus.MustRegister(c.listingActive)
+	prometheus.MustRegister(c.sourceReads)
+	prometheus.MustRegister(c.aclDropped)
 
 	return c
 }
@@ -71,10 +143,18 @@ func (c *Collector) IncSuccessWithBytes(bytes int64) {
 	c.progressTracker.AddSuccess(bytes)
 }
 
-// IncFailed increments failed object counter
-func (c *Collector) IncFailed() {
+// AddContentTypeBytes records a migrated object in the per-content-type breakdown
+func (c *Collector) AddContentTypeBytes(contentType string, bytes int64) {
+	c.progressTracker.AddContentType(contentType, bytes)
+}
+
+// IncFailed increments failed object counter under the given error category,
+// counting the bytes of the object as failed
+func (c *Collector) IncFailed(category string, bytes int64) {
 	c.objectsTotal.WithLabelValues("failed").Inc()
-	c.progressTracker.AddFailed() // Update progress tracker
+	c.failuresTotal.WithLabelValues(category).Inc()
+	c.bytesFailed.Add(float64(bytes))
+	c.progressTracker.AddFailed(category, bytes) // Update progress tracker
 }
 
 // IncSkipped increments skipped object counter
@@ -85,10 +165,84 @@ func (c *Collector) IncSkipped() {
 // IncSkippedWithBytes increments skipped object counter and updates progress
 func (c *Collector) IncSkippedWithBytes(bytes int64) {
 	c.objectsTotal.WithLabelValues("skipped").Inc()
+	c.bytesSkipped.Add(float64(bytes))
 	c.progressTracker.AddSkipped(bytes)
 }
 
-// AddBytes adds to total bytes migrated
+// IncSkippedCompleted counts an object skipped because the checkpoint already
+// marks it completed. When progress was restored from a previous run the object
+// is already part of the restored counters, so progress is left untouched.
+func (c *Collector) IncSkippedCompleted(bytes int64) {
+	c.objectsTotal.WithLabelValues("skipped").Inc()
+	c.bytesSkipped.Add(float64(bytes))
+	if !c.progressTracker.Restored() {
+		c.progressTracker.AddSkipped(bytes)
+	}
+}
+
+// IncLocked counts an object skipped because the destination object is
+// locked (object lock/WORM) and cannot be overwritten
+func (c *Collector) IncLocked() {
+	c.objectsTotal.WithLabelValues("locked").Inc()
+	c.progressTracker.AddLocked()
+}
+
+// IncUnreadable counts an object skipped with --skip-unreadable because the
+// source object could not be read
+func (c *Collector) IncUnreadable() {
+	c.objectsTotal.WithLabelValues("unreadable").Inc()
+	c.progressTracker.AddUnreadable()
+}
+
+// IncExpiring counts an object skipped because the source lifecycle rules
+// expire it soon
+func (c *Collector) IncExpiring() {
+	c.objectsTotal.WithLabelValues("expiring").Inc()
+	c.progressTracker.AddExpiring()
+}
+
+// StartWorkerTask records the object worker id started transferring
+func (c *Collector) StartWorkerTask(id int, key string, size int64) {
+	c.progressTracker.StartWorkerTask(id, key, size)
+}
+
+// AddWorkerBytes adds to the bytes transferred by worker id for its current object
+func (c *Collector) AddWorkerBytes(id int, bytes int64) {
+	c.progressTracker.AddWorkerBytes(id, bytes)
+}
+
+// FinishWorkerTask marks worker id as idle
+func (c *Collector) FinishWorkerTask(id int) {
+	c.progressTracker.FinishWorkerTask(id)
+}
+
+// IncMetadataUpdated counts an object whose data already matched and only had
+// its metadata updated on the destination
+func (c *Collector) IncMetadataUpdated() {
+	c.objectsTotal.WithLabelValues("metadata_updated").Inc()
+	c.progressTracker.AddMetadataUpdated()
+}
+
+// StartListing marks the start of a source listing described by stage
+func (c *Collector) StartListing(stage string) {
+	c.listedObjects.Set(0)
+	c.listingActive.Set(1)
+	c.progressTracker.StartListing(stage)
+}
+
+// AddDiscovered counts an object found by the current listing
+func (c *Collector) AddDiscovered(bytes int64) {
+	c.listedObjects.Inc()
+	c.progressTracker.AddDiscovered(bytes)
+}
+
+// FinishListing marks the end of the listing phase
+func (c *Collector) FinishListing() {
+	c.listingActive.Set(0)
+	c.progressTracker.FinishListing()
+}
+
+// AddBytes adds to total bytes transferred to the destination
 func (c *Collector) AddBytes(bytes int64) {
 	c.bytesTotal.Add(float64(bytes))
 }
@@ -103,10 +257,55 @@ func (c *Collector) ObserveDuration(duration time.Duration) {
 	c.duration.Observe(duration.Seconds())
 }
 
-// StartServer starts the metrics HTTP server
+// IncACLGrantDropped counts a source ACL grant that could not be applied
+func (c *Collector) IncACLGrantDropped() {
+	c.aclDropped.Inc()
+}
+
+// SetSourceReads sets the number of source reads currently holding a slot
+func (c *Collector) SetSourceReads(n int) {
+	c.sourceReads.Set(float64(n))
+}
+
+// SetThrottleDelay sets the current auto-throttle delay
+func (c *Collector) SetThrottleDelay(delay time.Duration) {
+	c.throttleDelay.Set(delay.Seconds())
+}
+
+// StartServer starts the metrics HTTP server. It blocks until the server
+// fails or Shutdown stops it, in which case it returns nil.
 func (c *Collector) StartServer(addr string) error {
-	http.Handle("/metrics", promhttp.Handler())
-	return http.ListenAndServe(addr, nil)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	c.serverMu.Lock()
+	if c.serverClosed {
+		c.serverMu.Unlock()
+		return nil
+	}
+	c.server = server
+	c.serverMu.Unlock()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the metrics HTTP server, releasing its port. In-flight
+// scrapes are given until ctx is done to finish.
+func (c *Collector) Shutdown(ctx context.Context) error {
+	c.serverMu.Lock()
+	server := c.server
+	c.server = nil
+	c.serverClosed = true
+	c.serverMu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
 
 // GetProgressTracker returns the progress tracker
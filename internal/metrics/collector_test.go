@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestByteCounters(t *testing.T) {
+	c := New()
+	c.AddBytes(100)
+	c.IncSkippedWithBytes(20)
+	c.IncSkippedCompleted(3)
+	c.IncFailed("network", 7)
+
+	if got := testutil.ToFloat64(c.bytesTotal); got != 100 {
+		t.Errorf("transferred bytes %v, want 100", got)
+	}
+	if got := testutil.ToFloat64(c.bytesSkipped); got != 23 {
+		t.Errorf("skipped bytes %v, want 23", got)
+	}
+	if got := testutil.ToFloat64(c.bytesFailed); got != 7 {
+		t.Errorf("failed bytes %v, want 7", got)
+	}
+
+	// Existing queries sum migrate_bytes_total as transferred bytes, so it
+	// must stay a single unlabeled series
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	found := false
+	for _, family := range families {
+		if family.GetName() != "migrate_bytes_total" {
+			continue
+		}
+		found = true
+		if len(family.GetMetric()) != 1 || len(family.GetMetric()[0].GetLabel()) != 0 {
+			t.Errorf("migrate_bytes_total has series %v, want one unlabeled series", family.GetMetric())
+		}
+	}
+	if !found {
+		t.Errorf("migrate_bytes_total not registered")
+	}
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	webhookAttempts = 3
+	webhookBackoff  = time.Second
+	failureQueue    = 256
+)
+
+// CompletionEvent is posted once when a run finishes
+type CompletionEvent struct {
+	Event            string  `json:"event"` // Always "completed"
+	RunID            string  `json:"run_id"`
+	Success          int64   `json:"success"`
+	Skipped          int64   `json:"skipped"`
+	Failed           int64   `json:"failed"`
+	Unreadable       int64   `json:"unreadable"` // Skipped with --skip-unreadable
+	TransferredBytes int64   `json:"transferred_bytes"`
+	SkippedBytes     int64   `json:"skipped_bytes"`
+	FailedBytes      int64   `json:"failed_bytes"`
+	DurationSeconds  float64 `json:"duration_seconds"`
+}
+
+// FailureEvent is posted for each object that failed after all retries
+type FailureEvent struct {
+	Event  string `json:"event"` // Always "object_failed"
+	RunID  string `json:"run_id"`
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	Error  string `json:"error"`
+}
+
+// Webhook POSTs JSON events to a URL, retrying transient failures. Failure
+// events are queued and sent in the background so that workers never wait on
+// the webhook; when the queue is full they are dropped with a warning.
+type Webhook struct {
+	url      string
+	client   *http.Client
+	logger   *zap.Logger
+	failures chan FailureEvent
+	cancel   context.CancelFunc // Aborts queued failure events that outlive Close
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex // Guards closed against workers still failing after Close
+	closed bool
+}
+
+// NewWebhook creates a webhook posting to url
+func NewWebhook(url string, logger *zap.Logger) *Webhook {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Webhook{
+		url:      url,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+		failures: make(chan FailureEvent, failureQueue),
+		cancel:   cancel,
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for event := range w.failures {
+			if ctx.Err() != nil {
+				continue
+			}
+			if err := w.post(ctx, event); err != nil {
+				w.logger.Warn("Failed to post failure webhook", zap.String("key", event.Key), zap.Error(err))
+			}
+		}
+	}()
+	return w
+}
+
+// NotifyFailure queues a failure event without blocking. Events arriving
+// after Close are dropped.
+func (w *Webhook) NotifyFailure(event FailureEvent) {
+	event.Event = "object_failed"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return
+	}
+	select {
+	case w.failures <- event:
+	default:
+		w.logger.Warn("Failure webhook queue full, dropping event", zap.String("key", event.Key))
+	}
+}
+
+// NotifyCompletion posts a completion event and waits for it to be delivered
+func (w *Webhook) NotifyCompletion(ctx context.Context, event CompletionEvent) error {
+	event.Event = "completed"
+	return w.post(ctx, event)
+}
+
+// Close sends the queued failure events and stops the background sender.
+// Events still unsent when ctx is done are dropped.
+func (w *Webhook) Close(ctx context.Context) {
+	w.mu.Lock()
+	w.closed = true
+	close(w.failures)
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		w.logger.Warn("Timed out sending queued failure webhooks")
+		w.cancel()
+		<-done
+	}
+	w.cancel()
+}
+
+// post sends event, retrying network errors and 5xx/429 responses
+func (w *Webhook) post(ctx context.Context, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := w.send(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == webhookAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// send performs a single POST and reports whether a failure may be retried
+func (w *Webhook) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("webhook returned %s", resp.Status)
+}
@@ -3,6 +3,7 @@ package progress
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,18 +13,37 @@ type Display struct {
 	tracker   *Tracker
 	interval  time.Duration
 	stopCh    chan struct{}
-	lastLines int // 记录上次输出的行数，用于清屏
+	lastLines int    // 记录上次输出的行数，用于清屏
+	workers   bool   // 显示每个 worker 当前处理的对象
+	theme     *Theme // 标签与进度条字符
 }
 
+const (
+	defaultTerminalLines = 24
+	maxWorkerKeyWidth    = 60
+)
+
 // NewDisplay creates a new progress display
 func NewDisplay(tracker *Tracker, interval time.Duration) *Display {
 	return &Display{
 		tracker:  tracker,
 		interval: interval,
 		stopCh:   make(chan struct{}),
+		theme:    ThemeUnicode,
 	}
 }
 
+// SetTheme selects the labels and progress bar characters of the display
+func (d *Display) SetTheme(theme *Theme) {
+	d.theme = theme
+}
+
+// SetShowWorkers enables the per-worker section listing the object each
+// active worker is transferring
+func (d *Display) SetShowWorkers(show bool) {
+	d.workers = show
+}
+
 // Start starts the progress display
 func (d *Display) Start() {
 	go d.displayLoop()
@@ -85,16 +105,30 @@ func (d *Display) clearLines() {
 
 // generateDisplay generates the progress display lines
 func (d *Display) generateDisplay(status Status) []string {
+	t := d.theme
 	lines := make([]string, 0)
 
 	// 标题
 	lines = append(lines, "")
-	lines = append(lines, "🚀 对象迁移进度")
+	lines = append(lines, t.title)
 	lines = append(lines, "="+strings.Repeat("=", 50))
 
+	// 列举阶段：在任务全部下发前显示发现进度，避免看起来像卡住
+	if status.Listing {
+		lines = d.appendListingLines(lines, status)
+		// 统计阶段还没有任何传输，只显示列举信息
+		if status.ProcessedObjects == 0 && status.TotalObjects == 0 {
+			lines = append(lines, "")
+			lines = append(lines, fmt.Sprintf("%s: %s", t.updated, time.Now().Format("15:04:05")))
+			lines = append(lines, "")
+			return lines
+		}
+		lines = append(lines, "")
+	}
+
 	// 对象统计
 	objectProgress := d.tracker.GetProgressPercent()
-	lines = append(lines, fmt.Sprintf("📊 对象进度: %d/%d (%.1f%%)",
+	lines = append(lines, fmt.Sprintf(t.objectProgress,
 		status.ProcessedObjects, status.TotalObjects, objectProgress))
 
 	// 进度条
@@ -103,7 +137,7 @@ func (d *Display) generateDisplay(status Status) []string {
 
 	// 字节统计
 	bytesProgress := d.tracker.GetBytesProgressPercent()
-	lines = append(lines, fmt.Sprintf("💾 数据进度: %s/%s (%.1f%%)",
+	lines = append(lines, fmt.Sprintf(t.dataProgress,
 		FormatBytes(status.ProcessedBytes), FormatBytes(status.TotalBytes), bytesProgress))
 
 	// 字节进度条
@@ -112,59 +146,177 @@ func (d *Display) generateDisplay(status Status) []string {
 
 	// 详细统计
 	lines = append(lines, "")
-	lines = append(lines, "📈 详细统计:")
-	lines = append(lines, fmt.Sprintf("  ✅ 成功: %d", status.SuccessObjects))
-	lines = append(lines, fmt.Sprintf("  ❌ 失败: %d", status.FailedObjects))
-	lines = append(lines, fmt.Sprintf("  ⏭️  跳过: %d", status.SkippedObjects))
+	lines = append(lines, t.details)
+	lines = append(lines, fmt.Sprintf("  %s: %d", t.success, status.SuccessObjects))
+	lines = append(lines, fmt.Sprintf("  %s: %d", t.failed, status.FailedObjects))
+	lines = append(lines, fmt.Sprintf("  %s: %d", t.skipped, status.SkippedObjects))
+	if status.LockedObjects > 0 {
+		lines = append(lines, fmt.Sprintf("  %s: %d", t.locked, status.LockedObjects))
+	}
+	if status.Unreadable > 0 {
+		lines = append(lines, fmt.Sprintf("  %s: %d", t.unreadable, status.Unreadable))
+	}
+	if status.ExpiringObjects > 0 {
+		lines = append(lines, fmt.Sprintf("  %s: %d", t.expiring, status.ExpiringObjects))
+	}
+	if status.MetadataObjects > 0 {
+		lines = append(lines, fmt.Sprintf("  %s: %d", t.metadata, status.MetadataObjects))
+	}
+
+	// Worker 状态
+	if d.workers {
+		lines = d.appendWorkerLines(lines)
+	}
 
 	// 速度信息
 	lines = append(lines, "")
-	lines = append(lines, "⚡ 速度信息:")
-	lines = append(lines, fmt.Sprintf("  当前速度: %s", FormatSpeed(status.CurrentSpeed)))
-	lines = append(lines, fmt.Sprintf("  平均速度: %s", FormatSpeed(status.AverageSpeed)))
+	lines = append(lines, t.speed)
+	lines = append(lines, fmt.Sprintf("  %s: %s", t.currentSpeed, FormatSpeed(status.CurrentSpeed)))
+	lines = append(lines, fmt.Sprintf("  %s: %s", t.averageSpeed, FormatSpeed(status.AverageSpeed)))
 
 	// 时间信息
 	elapsed := time.Since(status.StartTime)
 	lines = append(lines, "")
-	lines = append(lines, "⏱️  时间信息:")
-	lines = append(lines, fmt.Sprintf("  已用时间: %s", FormatDuration(elapsed)))
-	lines = append(lines, fmt.Sprintf("  预计剩余: %s", FormatDuration(status.ETA)))
+	lines = append(lines, t.timing)
+	lines = append(lines, fmt.Sprintf("  %s: %s", t.elapsed, d.formatDuration(elapsed)))
+	lines = append(lines, fmt.Sprintf("  %s: %s", t.eta, d.formatDuration(status.ETA)))
 
 	// 如果有预计完成时间
 	if status.ETA > 0 {
 		estimatedCompletion := time.Now().Add(status.ETA)
-		lines = append(lines, fmt.Sprintf("  预计完成: %s", estimatedCompletion.Format("15:04:05")))
+		lines = append(lines, fmt.Sprintf("  %s: %s", t.completion, estimatedCompletion.Format("15:04:05")))
 	}
 
 	lines = append(lines, "")
-	lines = append(lines, fmt.Sprintf("⏰ 最后更新: %s", status.LastUpdateTime.Format("15:04:05")))
+	lines = append(lines, fmt.Sprintf("%s: %s", t.updated, status.LastUpdateTime.Format("15:04:05")))
+	lines = append(lines, "")
+
+	return lines
+}
+
+// appendListingLines appends the discovery progress of the current listing
+func (d *Display) appendListingLines(lines []string, status Status) []string {
+	t := d.theme
+	lines = append(lines, fmt.Sprintf(t.listing, t.stage(status.ListingStage)))
+	lines = append(lines, fmt.Sprintf(t.discovered, status.DiscoveredObjects, FormatBytes(status.DiscoveredBytes)))
+	lines = append(lines, fmt.Sprintf(t.discoveryRate, status.DiscoveryRate()))
+	lines = append(lines, fmt.Sprintf("%s: %s", t.listingTime, d.formatDuration(time.Since(status.ListingStart))))
+	return lines
+}
+
+// appendWorkerLines appends one line per busy worker. The number of workers
+// shown is capped so that the whole display still fits the terminal height.
+func (d *Display) appendWorkerLines(lines []string) []string {
+	states := d.tracker.WorkerStates()
+
 	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf(d.theme.workers, len(states)))
+
+	// 速度、时间信息大约还占 12 行
+	limit := terminalLines() - len(lines) - 12
+	if limit < 1 {
+		limit = 1
+	}
+	shown := states
+	if len(shown) > limit {
+		shown = shown[:limit]
+	}
+
+	for _, state := range shown {
+		percent := 0.0
+		if state.Size > 0 {
+			percent = float64(state.Bytes) / float64(state.Size) * 100
+		}
+		lines = append(lines, fmt.Sprintf("  #%-3d %-*s %s/%s (%.1f%%)",
+			state.ID, maxWorkerKeyWidth, truncateKey(state.Key, maxWorkerKeyWidth),
+			FormatBytes(state.Bytes), FormatBytes(state.Size), percent))
+	}
+	if hidden := len(states) - len(shown); hidden > 0 {
+		lines = append(lines, fmt.Sprintf(d.theme.hiddenWorkers, hidden))
+	}
 
 	return lines
 }
 
+// truncateKey shortens key to width runes, keeping its end which usually
+// identifies the object best
+func truncateKey(key string, width int) string {
+	runes := []rune(key)
+	if len(runes) <= width {
+		return key
+	}
+	return "..." + string(runes[len(runes)-width+3:])
+}
+
+// terminalLines returns the terminal height from $LINES, falling back to a
+// conventional 24 lines
+func terminalLines() int {
+	if lines, err := strconv.Atoi(os.Getenv("LINES")); err == nil && lines > 0 {
+		return lines
+	}
+	return defaultTerminalLines
+}
+
 // generateFinalDisplay generates the final completion display
 func (d *Display) generateFinalDisplay(status Status) []string {
+	t := d.theme
 	lines := make([]string, 0)
 
 	elapsed := time.Since(status.StartTime)
 
 	lines = append(lines, "")
-	lines = append(lines, "🎉 迁移完成!")
+	lines = append(lines, t.finalTitle)
 	lines = append(lines, "="+strings.Repeat("=", 50))
 
-	lines = append(lines, fmt.Sprintf("📊 总计处理: %d 个对象", status.ProcessedObjects))
-	lines = append(lines, fmt.Sprintf("💾 总计数据: %s", FormatBytes(status.ProcessedBytes)))
-	lines = append(lines, fmt.Sprintf("✅ 成功: %d", status.SuccessObjects))
-	lines = append(lines, fmt.Sprintf("❌ 失败: %d", status.FailedObjects))
-	lines = append(lines, fmt.Sprintf("⏭️  跳过: %d", status.SkippedObjects))
-	lines = append(lines, fmt.Sprintf("⏱️  总用时: %s", FormatDuration(elapsed)))
-	lines = append(lines, fmt.Sprintf("⚡ 平均速度: %s", FormatSpeed(status.AverageSpeed)))
+	lines = append(lines, fmt.Sprintf(t.totalObjects, status.ProcessedObjects))
+	lines = append(lines, fmt.Sprintf("%s: %s", t.totalData, FormatBytes(status.ProcessedBytes)))
+	lines = append(lines, fmt.Sprintf("    - %s %s", t.transferred, FormatBytes(status.TransferredBytes)))
+	lines = append(lines, fmt.Sprintf("    - %s %s", t.existingSkipped, FormatBytes(status.SkippedBytes)))
+	if status.FailedBytes > 0 {
+		lines = append(lines, fmt.Sprintf("    - %s %s", t.failedBytes, FormatBytes(status.FailedBytes)))
+	}
+	lines = append(lines, fmt.Sprintf("%s: %d", t.success, status.SuccessObjects))
+	lines = append(lines, fmt.Sprintf("%s: %d", t.failed, status.FailedObjects))
+	for _, stat := range d.tracker.FailureCategories() {
+		lines = append(lines, fmt.Sprintf("    - %-10s %d", stat.Category, stat.Objects))
+	}
+	lines = append(lines, fmt.Sprintf("%s: %d", t.skipped, status.SkippedObjects))
+	if status.LockedObjects > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %d", t.locked, status.LockedObjects))
+	}
+	if status.Unreadable > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %d", t.unreadable, status.Unreadable))
+	}
+	if status.ExpiringObjects > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %d", t.expiring, status.ExpiringObjects))
+	}
+	if status.MetadataObjects > 0 {
+		lines = append(lines, fmt.Sprintf("%s: %d", t.metadata, status.MetadataObjects))
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", t.totalTime, d.formatDuration(elapsed)))
+	lines = append(lines, fmt.Sprintf("%s: %s", t.finalSpeed, FormatSpeed(status.AverageSpeed)))
+
+	// 按内容类型统计
+	if contentTypes := d.tracker.TopContentTypes(5); len(contentTypes) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, t.contentTypes)
+		for _, stat := range contentTypes {
+			lines = append(lines, fmt.Sprintf("  %-32s %8d %s  %s", stat.ContentType, stat.Objects, t.contentTypeCount, FormatBytes(stat.Bytes)))
+		}
+	}
 	lines = append(lines, "")
 
 	return lines
 }
 
+// formatDuration formats a duration with the labels of the theme
+func (d *Display) formatDuration(duration time.Duration) string {
+	if duration == 0 {
+		return d.theme.calculating
+	}
+	return FormatDuration(duration)
+}
+
 // generateProgressBar generates a visual progress bar
 func (d *Display) generateProgressBar(percent float64, width int) string {
 	if percent > 100 {
@@ -175,7 +327,7 @@ func (d *Display) generateProgressBar(percent float64, width int) string {
 	}
 
 	filled := int(percent * float64(width) / 100)
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+	bar := strings.Repeat(d.theme.barFilled, filled) + strings.Repeat(d.theme.barEmpty, width-filled)
 
 	return fmt.Sprintf("[%s] %.1f%%", bar, percent)
 }
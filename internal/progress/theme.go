@@ -0,0 +1,165 @@
+package progress
+
+// Listing stages shown while objects are listed
+const (
+	StageCounting    = "统计对象数量"
+	StageDispatching = "下发迁移任务"
+)
+
+// Theme holds the labels and progress bar characters of the display
+type Theme struct {
+	title      string
+	finalTitle string
+
+	listing       string // Format with the listing stage
+	discovered    string // Format with objects and size
+	discoveryRate string // Format with objects per second
+	listingTime   string
+	stages        map[string]string // Stage names replaced in listing, if any
+
+	objectProgress string // Format with processed, total and percent
+	dataProgress   string // Format with processed size, total size and percent
+	details        string
+	success        string
+	failed         string
+	skipped        string
+	locked         string
+	unreadable     string
+	expiring       string
+	metadata       string
+
+	workers       string // Format with the number of active workers
+	hiddenWorkers string // Format with the number of workers not shown
+
+	speed        string
+	currentSpeed string
+	averageSpeed string
+	timing       string
+	elapsed      string
+	eta          string
+	completion   string
+	updated      string
+	calculating  string // Shown for a duration that is not known yet
+
+	totalObjects     string // Format with the number of objects
+	totalData        string
+	transferred      string
+	existingSkipped  string
+	failedBytes      string
+	totalTime        string
+	finalSpeed       string
+	contentTypes     string
+	contentTypeCount string // Unit after the object count of a content type
+
+	barFilled string
+	barEmpty  string
+}
+
+// ThemeUnicode is the default theme, with emoji and block characters
+var ThemeUnicode = &Theme{
+	title:      "🚀 对象迁移进度",
+	finalTitle: "🎉 迁移完成!",
+
+	listing:       "🔍 正在列举对象（%s）",
+	discovered:    "  已发现: %d 个对象, %s",
+	discoveryRate: "  发现速度: %.0f 个对象/秒",
+	listingTime:   "  列举用时",
+
+	objectProgress: "📊 对象进度: %d/%d (%.1f%%)",
+	dataProgress:   "💾 数据进度: %s/%s (%.1f%%)",
+	details:        "📈 详细统计:",
+	success:        "✅ 成功",
+	failed:         "❌ 失败",
+	skipped:        "⏭️  跳过",
+	locked:         "🔒 锁定无法覆盖",
+	unreadable:     "🚫 无法读取跳过",
+	expiring:       "⌛ 即将过期跳过",
+	metadata:       "🏷️  仅更新元数据",
+
+	workers:       "👷 Worker 状态 (%d 个活跃):",
+	hiddenWorkers: "  ... 另有 %d 个 worker 未显示",
+
+	speed:        "⚡ 速度信息:",
+	currentSpeed: "当前速度",
+	averageSpeed: "平均速度",
+	timing:       "⏱️  时间信息:",
+	elapsed:      "已用时间",
+	eta:          "预计剩余",
+	completion:   "预计完成",
+	updated:      "⏰ 最后更新",
+	calculating:  "计算中...",
+
+	totalObjects:     "📊 总计处理: %d 个对象",
+	totalData:        "💾 总计数据",
+	transferred:      "实际传输",
+	existingSkipped:  "已存在跳过",
+	failedBytes:      "失败未迁移",
+	totalTime:        "⏱️  总用时",
+	finalSpeed:       "⚡ 平均速度",
+	contentTypes:     "🗂️  内容类型 (Top 5):",
+	contentTypeCount: "个对象",
+
+	barFilled: "█",
+	barEmpty:  "░",
+}
+
+// ThemeASCII uses plain ASCII labels and bars, for terminals and log
+// collectors that cannot render emoji or CJK text
+var ThemeASCII = &Theme{
+	title:      "Migration progress",
+	finalTitle: "Migration completed",
+
+	listing:       "Listing objects (%s)",
+	discovered:    "  Discovered: %d objects, %s",
+	discoveryRate: "  Discovery rate: %.0f objects/s",
+	listingTime:   "  Listing time",
+	stages: map[string]string{
+		StageCounting:    "counting objects",
+		StageDispatching: "dispatching tasks",
+	},
+
+	objectProgress: "Objects: %d/%d (%.1f%%)",
+	dataProgress:   "Data: %s/%s (%.1f%%)",
+	details:        "Details:",
+	success:        "Succeeded",
+	failed:         "Failed",
+	skipped:        "Skipped",
+	locked:         "Locked",
+	unreadable:     "Unreadable",
+	expiring:       "Expiring",
+	metadata:       "Metadata only",
+
+	workers:       "Workers (%d active):",
+	hiddenWorkers: "  ... %d more workers not shown",
+
+	speed:        "Speed:",
+	currentSpeed: "Current",
+	averageSpeed: "Average",
+	timing:       "Time:",
+	elapsed:      "Elapsed",
+	eta:          "Remaining",
+	completion:   "Completes at",
+	updated:      "Last update",
+	calculating:  "calculating...",
+
+	totalObjects:     "Processed: %d objects",
+	totalData:        "Data",
+	transferred:      "transferred",
+	existingSkipped:  "skipped (existing)",
+	failedBytes:      "failed",
+	totalTime:        "Duration",
+	finalSpeed:       "Average speed",
+	contentTypes:     "Content types (Top 5):",
+	contentTypeCount: "objects",
+
+	barFilled: "#",
+	barEmpty:  "-",
+}
+
+// stage returns the name of a listing stage in the theme
+func (t *Theme) stage(stage string) string {
+	if name, ok := t.stages[stage]; ok {
+		return name
+	}
+	return stage
+}
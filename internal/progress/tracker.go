@@ -2,10 +2,19 @@ package progress
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	// maxContentTypes bounds the number of distinct content types tracked;
+	// anything beyond it is aggregated under otherContentType.
+	maxContentTypes  = 100
+	otherContentType = "other"
+)
+
 // Status represents the current migration status
 type Status struct {
 	TotalObjects     int64         // 总对象数量
@@ -13,13 +22,26 @@ type Status struct {
 	SuccessObjects   int64         // 成功对象数量
 	FailedObjects    int64         // 失败对象数量
 	SkippedObjects   int64         // 跳过对象数量
+	LockedObjects    int64         // 目标端对象锁定无法覆盖的对象数量
+	Unreadable       int64         // 源端无法读取而跳过的对象数量
+	ExpiringObjects  int64         // 源端即将过期而跳过的对象数量
+	MetadataObjects  int64         // 仅更新元数据的对象数量
 	TotalBytes       int64         // 总字节数
-	ProcessedBytes   int64         // 已处理字节数
+	ProcessedBytes   int64         // 已处理字节数（传输 + 跳过）
+	TransferredBytes int64         // 实际传输的字节数
+	SkippedBytes     int64         // 因已存在而跳过的字节数
+	FailedBytes      int64         // 失败对象的字节数
 	StartTime        time.Time     // 开始时间
 	LastUpdateTime   time.Time     // 最后更新时间
 	CurrentSpeed     float64       // 当前速度 (bytes/second)
 	AverageSpeed     float64       // 平均速度 (bytes/second)
 	ETA              time.Duration // 预计剩余时间
+
+	Listing           bool      // 是否处于列举阶段
+	ListingStage      string    // 列举阶段说明，如统计或下发任务
+	ListingStart      time.Time // 本次列举开始时间
+	DiscoveredObjects int64     // 本次列举已发现的对象数量
+	DiscoveredBytes   int64     // 本次列举已发现的字节数
 }
 
 // Tracker tracks migration progress
@@ -28,6 +50,32 @@ type Tracker struct {
 	status       Status
 	speedSamples []speedSample // 用于计算平均速度的样本
 	maxSamples   int           // 最大样本数量
+	contentTypes map[string]*ContentTypeStat
+	restored     bool // Counters include progress restored from a previous run
+	workers      map[int]*WorkerState
+	failures     map[string]int64 // Failed objects by error category
+}
+
+// FailureStat counts failed objects of a single error category
+type FailureStat struct {
+	Category string
+	Objects  int64
+}
+
+// WorkerState is the object a worker is currently transferring
+type WorkerState struct {
+	ID      int
+	Key     string
+	Size    int64
+	Bytes   int64 // Bytes read from the source so far in the current attempt
+	Started time.Time
+}
+
+// ContentTypeStat aggregates migrated objects of a single content type
+type ContentTypeStat struct {
+	ContentType string
+	Objects     int64
+	Bytes       int64
 }
 
 type speedSample struct {
@@ -44,6 +92,9 @@ func NewTracker() *Tracker {
 		},
 		speedSamples: make([]speedSample, 0, 60), // 保存60个样本点
 		maxSamples:   60,
+		contentTypes: make(map[string]*ContentTypeStat),
+		workers:      make(map[int]*WorkerState),
+		failures:     make(map[string]int64),
 	}
 }
 
@@ -56,6 +107,75 @@ func (t *Tracker) SetTotal(objects, bytes int64) {
 	t.status.TotalBytes = bytes
 }
 
+// StartListing switches to the listing phase and resets the discovery
+// counters. stage describes what the listing is for and is shown as-is.
+func (t *Tracker) StartListing(stage string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Listing = true
+	t.status.ListingStage = stage
+	t.status.ListingStart = time.Now()
+	t.status.DiscoveredObjects = 0
+	t.status.DiscoveredBytes = 0
+}
+
+// AddDiscovered counts an object found by the current listing
+func (t *Tracker) AddDiscovered(bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.DiscoveredObjects++
+	t.status.DiscoveredBytes += bytes
+}
+
+// FinishListing ends the listing phase; the display switches to transfer progress
+func (t *Tracker) FinishListing() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Listing = false
+}
+
+// DiscoveryRate returns the objects discovered per second by the current listing
+func (s Status) DiscoveryRate() float64 {
+	elapsed := time.Since(s.ListingStart).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.DiscoveredObjects) / elapsed
+}
+
+// Restore seeds the counters with progress persisted by a previous run. Start
+// time is moved back by the previously elapsed time so that average speed and
+// ETA reflect cumulative progress rather than just the current session.
+// Progress saved by older versions has no transferred/skipped split, so those
+// bytes only count as processed.
+func (t *Tracker) Restore(successObjects, skippedObjects, processedBytes, transferredBytes, skippedBytes int64, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.SuccessObjects = successObjects
+	t.status.SkippedObjects = skippedObjects
+	t.status.ProcessedObjects = successObjects + skippedObjects
+	t.status.ProcessedBytes = processedBytes
+	t.status.TransferredBytes = transferredBytes
+	t.status.SkippedBytes = skippedBytes
+	t.status.StartTime = time.Now().Add(-elapsed)
+	t.restored = true
+
+	t.calculateAverageSpeed(time.Now())
+	t.calculateETA()
+}
+
+// Restored reports whether the counters were seeded by Restore
+func (t *Tracker) Restored() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.restored
+}
+
 // AddSuccess increments successful objects count
 func (t *Tracker) AddSuccess(bytes int64) {
 	t.mu.Lock()
@@ -64,15 +184,58 @@ func (t *Tracker) AddSuccess(bytes int64) {
 	t.status.SuccessObjects++
 	t.status.ProcessedObjects++
 	t.status.ProcessedBytes += bytes
+	t.status.TransferredBytes += bytes
 	t.updateSpeed(bytes)
 }
 
-// AddFailed increments failed objects count
-func (t *Tracker) AddFailed() {
+// AddFailed increments failed objects count under the given error category
+func (t *Tracker) AddFailed(category string, bytes int64) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
 	t.status.FailedObjects++
+	t.status.FailedBytes += bytes
+	t.failures[category]++
+	t.status.ProcessedObjects++
+}
+
+// AddLocked increments the count of objects skipped because the destination
+// object is locked
+func (t *Tracker) AddLocked() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.LockedObjects++
+	t.status.ProcessedObjects++
+}
+
+// AddUnreadable increments the count of objects skipped because the source
+// object could not be read
+func (t *Tracker) AddUnreadable() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.Unreadable++
+	t.status.ProcessedObjects++
+}
+
+// AddExpiring increments the count of objects skipped because they expire
+// soon on the source
+func (t *Tracker) AddExpiring() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.ExpiringObjects++
+	t.status.ProcessedObjects++
+}
+
+// AddMetadataUpdated increments the count of objects that only had their
+// metadata updated
+func (t *Tracker) AddMetadataUpdated() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.status.MetadataObjects++
 	t.status.ProcessedObjects++
 }
 
@@ -84,9 +247,128 @@ func (t *Tracker) AddSkipped(bytes int64) {
 	t.status.SkippedObjects++
 	t.status.ProcessedObjects++
 	t.status.ProcessedBytes += bytes
+	t.status.SkippedBytes += bytes
 	t.updateSpeed(bytes)
 }
 
+// AddContentType records a migrated object under its content type
+func (t *Tracker) AddContentType(contentType string, bytes int64) {
+	contentType = normalizeContentType(contentType)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stat, ok := t.contentTypes[contentType]
+	if !ok {
+		if len(t.contentTypes) >= maxContentTypes {
+			contentType = otherContentType
+			stat = t.contentTypes[contentType]
+		}
+		if stat == nil {
+			stat = &ContentTypeStat{ContentType: contentType}
+			t.contentTypes[contentType] = stat
+		}
+	}
+
+	stat.Objects++
+	stat.Bytes += bytes
+}
+
+// FailureCategories returns failed object counts per error category, largest first
+func (t *Tracker) FailureCategories() []FailureStat {
+	t.mu.RLock()
+	stats := make([]FailureStat, 0, len(t.failures))
+	for category, objects := range t.failures {
+		stats = append(stats, FailureStat{Category: category, Objects: objects})
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Objects != stats[j].Objects {
+			return stats[i].Objects > stats[j].Objects
+		}
+		return stats[i].Category < stats[j].Category
+	})
+	return stats
+}
+
+// TopContentTypes returns up to n content types ordered by migrated bytes
+func (t *Tracker) TopContentTypes(n int) []ContentTypeStat {
+	t.mu.RLock()
+	stats := make([]ContentTypeStat, 0, len(t.contentTypes))
+	for _, stat := range t.contentTypes {
+		stats = append(stats, *stat)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].ContentType < stats[j].ContentType
+	})
+
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	return stats
+}
+
+// StartWorkerTask records that worker id started transferring key. Calling it
+// again for a retry resets the transferred bytes.
+func (t *Tracker) StartWorkerTask(id int, key string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.workers[id] = &WorkerState{ID: id, Key: key, Size: size, Started: time.Now()}
+}
+
+// AddWorkerBytes adds to the bytes transferred by worker id for its current object
+func (t *Tracker) AddWorkerBytes(id int, bytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.workers[id]; ok {
+		state.Bytes += bytes
+	}
+}
+
+// FinishWorkerTask marks worker id as idle
+func (t *Tracker) FinishWorkerTask(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.workers, id)
+}
+
+// WorkerStates returns the current object of every busy worker, ordered by worker ID
+func (t *Tracker) WorkerStates() []WorkerState {
+	t.mu.RLock()
+	states := make([]WorkerState, 0, len(t.workers))
+	for _, state := range t.workers {
+		states = append(states, *state)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].ID < states[j].ID
+	})
+	return states
+}
+
+// normalizeContentType drops parameters such as charset so that
+// "text/plain" and "text/plain; charset=utf-8" are counted together
+func normalizeContentType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return "unknown"
+	}
+	return contentType
+}
+
 // updateSpeed updates the speed calculation (must be called with lock held)
 func (t *Tracker) updateSpeed(bytes int64) {
 	now := time.Now()
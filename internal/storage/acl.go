@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Grantee types of an ACL grant
+const (
+	GranteeCanonicalUser = "CanonicalUser"
+	GranteeGroup         = "Group"
+	GranteeEmail         = "AmazonCustomerByEmail"
+)
+
+// Grant gives one grantee a permission on an object
+type Grant struct {
+	GranteeType string // GranteeCanonicalUser, GranteeGroup or GranteeEmail; empty if unknown
+	Grantee     string // Canonical user ID, group URI or email address
+	Permission  string // READ, WRITE, READ_ACP, WRITE_ACP or FULL_CONTROL
+}
+
+func (g Grant) String() string {
+	return fmt.Sprintf("%s %s:%s", g.Permission, g.GranteeType, g.Grantee)
+}
+
+// ACL is the access control list of an object
+type ACL struct {
+	Owner  string // Canonical user ID of the object owner
+	Grants []Grant
+}
+
+// grantHeaders maps the object permissions that can be granted on upload to
+// their request header. WRITE has no object-level header.
+var grantHeaders = map[string]string{
+	"READ":         "X-Amz-Grant-Read",
+	"READ_ACP":     "X-Amz-Grant-Read-Acp",
+	"WRITE_ACP":    "X-Amz-Grant-Write-Acp",
+	"FULL_CONTROL": "X-Amz-Grant-Full-Control",
+}
+
+// granteeKeys maps grantee types to their key in a grant header value
+var granteeKeys = map[string]string{
+	GranteeCanonicalUser: "id",
+	GranteeGroup:         "uri",
+	GranteeEmail:         "emailAddress",
+}
+
+// CanApply reports whether g can be set on an uploaded object with the
+// x-amz-grant-* request headers
+func (g Grant) CanApply() bool {
+	_, perm := grantHeaders[g.Permission]
+	_, grantee := granteeKeys[g.GranteeType]
+	return perm && grantee && g.Grantee != ""
+}
+
+// withGrants returns metadata extended with the x-amz-grant-* headers for
+// grants. Grants that cannot be applied are left out; callers filter them
+// with CanApply and report them beforehand. metadata is not modified.
+func withGrants(metadata map[string]string, grants []Grant) map[string]string {
+	if len(grants) == 0 {
+		return metadata
+	}
+
+	values := make(map[string][]string)
+	for _, g := range grants {
+		if !g.CanApply() {
+			continue
+		}
+		header := grantHeaders[g.Permission]
+		values[header] = append(values[header], fmt.Sprintf("%s=%q", granteeKeys[g.GranteeType], g.Grantee))
+	}
+
+	merged := make(map[string]string, len(metadata)+len(values))
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	for header, grantees := range values {
+		merged[header] = strings.Join(grantees, ", ")
+	}
+	return merged
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/minio/minio-go/v7/pkg/signer"
+)
+
+// Capacity is the space of a destination, in bytes
+type Capacity struct {
+	Total int64
+	Free  int64
+}
+
+// CapacityReporter is implemented by clients that can report the capacity of
+// their endpoint. Clients that cannot are detected with a type assertion.
+type CapacityReporter interface {
+	Capacity(ctx context.Context) (Capacity, error)
+}
+
+// storageInfoPath is the MinIO admin API endpoint reporting disk usage. RustFS
+// serves the same API for compatibility with mc admin.
+const storageInfoPath = "/minio/admin/v3/storageinfo"
+
+// emptySHA256 is the SHA-256 of an empty payload, signed into admin requests
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// storageInfo is the subset of the admin storage info response used here
+type storageInfo struct {
+	Disks []struct {
+		TotalSpace uint64 `json:"totalspace"`
+		AvailSpace uint64 `json:"availspace"`
+	} `json:"disks"`
+	Backend struct {
+		StandardSCData   []int `json:"standardSCData"`
+		StandardSCParity int   `json:"standardSCParity"`
+	} `json:"backend"`
+}
+
+// Capacity queries the admin API of the endpoint. The raw disk space is scaled
+// by the data share of the standard storage class, so the result estimates the
+// object data that fits rather than the bytes written with erasure parity. The
+// credentials need the admin:StorageInfo permission.
+func (c *MinIOClient) Capacity(ctx context.Context) (Capacity, error) {
+	u := *c.client.EndpointURL()
+	u.Path = storageInfoPath
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Capacity{}, err
+	}
+
+	creds, err := c.creds.Get()
+	if err != nil {
+		return Capacity{}, err
+	}
+	req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+	req = signer.SignV4(*req, creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken, "us-east-1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Capacity{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return Capacity{}, fmt.Errorf("storage info: %s: %w", resp.Status, ErrNotImplemented)
+	case resp.StatusCode != http.StatusOK:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Capacity{}, fmt.Errorf("storage info: %s: %s", resp.Status, body)
+	}
+
+	var info storageInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return Capacity{}, fmt.Errorf("invalid storage info response: %w", err)
+	}
+	if len(info.Disks) == 0 {
+		return Capacity{}, fmt.Errorf("storage info reported no disks")
+	}
+
+	var capacity Capacity
+	for _, disk := range info.Disks {
+		capacity.Total += int64(disk.TotalSpace)
+		capacity.Free += int64(disk.AvailSpace)
+	}
+	if parity := info.Backend.StandardSCParity; parity > 0 && len(info.Backend.StandardSCData) > 0 {
+		data := int64(info.Backend.StandardSCData[0])
+		capacity.Total = capacity.Total * data / (data + int64(parity))
+		capacity.Free = capacity.Free * data / (data + int64(parity))
+	}
+	return capacity, nil
+}
@@ -9,15 +9,48 @@ import (
 // Client defines the interface for S3-compatible storage operations
 type Client interface {
 	// Object operations
-	GetObject(ctx context.Context, bucket, key string) (Object, error)
-	PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) error
+	// GetObject reads a whole object; see GetOptions for conditional reads
+	GetObject(ctx context.Context, bucket, key string, opts GetOptions) (Object, error)
+	// GetObjectRange reads length bytes of an object from offset, or up to the
+	// end when length <= 0. A non-empty etag makes the read fail if the object
+	// has changed since it was listed.
+	GetObjectRange(ctx context.Context, bucket, key string, offset, length int64, etag string) (Object, error)
+	// PutObject uploads an object and returns the ETag reported by the server
+	PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) (string, error)
 	HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error)
-	ListObjects(ctx context.Context, bucket, prefix string) (<-chan ObjectInfo, <-chan error)
+	// GetObjectACL returns the owner and grants of an object
+	GetObjectACL(ctx context.Context, bucket, key string) (ACL, error)
+	// GetObjectTags returns the tag set of an object
+	GetObjectTags(ctx context.Context, bucket, key string) (map[string]string, error)
+	// PutObjectTags replaces the tag set of an object
+	PutObjectTags(ctx context.Context, bucket, key string, tags map[string]string) error
+	// ListObjects lists the objects under prefix; see ListOptions for
+	// listing only one level
+	ListObjects(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error)
+	// ListObjectsPage lists one page of objects under prefix, starting at the
+	// continuation token of the previous page or at the beginning when token
+	// is empty
+	ListObjectsPage(ctx context.Context, bucket, prefix, token string) (ListPage, error)
+	RemoveObject(ctx context.Context, bucket, key string) error
+	// UpdateMetadata replaces the content type and user metadata of an existing
+	// object with a server-side copy onto itself, without transferring data.
+	// A non-empty etag makes the copy fail if the object has changed.
+	UpdateMetadata(ctx context.Context, bucket, key, etag string, opts PutOptions) error
+	// ListPrefixes lists one level below prefix, returning the common
+	// prefixes ("directories") and the objects directly under it
+	ListPrefixes(ctx context.Context, bucket, prefix string) ([]string, []ObjectInfo, error)
+
+	// Bucket operations
+	BucketExists(ctx context.Context, bucket string) (bool, error)
+
+	// Notification operations
+	ListenBucketNotification(ctx context.Context, bucket, prefix string, events []string) (<-chan Event, <-chan error)
 
 	// Multipart operations
 	NewMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error)
 	UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error)
-	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error
+	// CompleteMultipartUpload returns the ETag of the assembled object
+	CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) (string, error)
 	AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error
 }
 
@@ -34,13 +67,67 @@ type ObjectInfo struct {
 	ETag         string
 	LastModified time.Time
 	ContentType  string // Add ContentType field
+	// ContentEncoding is the Content-Encoding header (e.g. gzip); the data is
+	// the encoded bytes, which are never decoded in transit
+	ContentEncoding string
+	Metadata        map[string]string
+	// Expiration is when the bucket's lifecycle rules expire the object
+	// (x-amz-expiration); zero when none applies or it is unknown. Only HEAD
+	// and GET responses carry it, listings do not.
+	Expiration time.Time
+}
+
+// ListPage is one page of a listing
+type ListPage struct {
+	Objects []ObjectInfo
+	// NextToken continues the listing after this page; empty on the last page
+	NextToken string
+}
+
+// Event represents a bucket notification event
+type Event struct {
+	Name         string // Event type, e.g. s3:ObjectCreated:Put
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
 	Metadata     map[string]string
+	LastModified time.Time
+}
+
+// GetOptions contains options for get operations
+type GetOptions struct {
+	// IfNoneMatch makes the read fail with a not-modified error when the
+	// object has this ETag, so that unchanged data is not transferred
+	IfNoneMatch string
+}
+
+// ListOptions contains options for list operations
+type ListOptions struct {
+	// NonRecursive lists only the objects directly under the prefix, using
+	// "/" as the delimiter; common prefixes ("directories") are left out
+	NonRecursive bool
 }
 
 // PutOptions contains options for put operations
 type PutOptions struct {
-	ContentType string
-	Metadata    map[string]string
+	ContentType     string
+	ContentEncoding string
+	Metadata        map[string]string
+	// Grants are applied with x-amz-grant-* headers; grants that cannot be
+	// expressed that way are ignored
+	Grants []Grant
+	// Tags are set on the new object with the x-amz-tagging header
+	Tags map[string]string
+	// DisableMultipart forces a single PUT request regardless of object size
+	DisableMultipart bool
+	// IfNoneMatch makes the upload fail with a precondition error when the
+	// existing object already has this ETag, or with "*" when the object
+	// exists at all. It only applies to single PUTs.
+	IfNoneMatch string
+	// IfMatch makes the upload fail with a precondition error unless the
+	// existing object has this ETag. It only applies to single PUTs.
+	IfMatch string
 }
 
 // CompletedPart represents a completed multipart upload part
@@ -55,4 +142,14 @@ type Config struct {
 	AccessKey string
 	SecretKey string
 	Secure    bool
+
+	CredentialsFile string // File with the keys and session token, reloaded when it changes; replaces AccessKey/SecretKey
+
+	ClientCert string // PEM client certificate presented for mutual TLS
+	ClientKey  string // PEM private key of ClientCert
+	CACert     string // PEM CA bundle trusted in addition to the system roots
+
+	Proxy string // HTTP(S) proxy URL, optionally with credentials; empty uses the environment
+
+	ListRateLimit float64 // Maximum list requests per second; 0 is unlimited
 }
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"gopkg.in/yaml.v3"
+)
+
+// credentialsCheckInterval is how often a credentials file is checked for
+// changes; requests in between reuse the loaded credentials
+const credentialsCheckInterval = 10 * time.Second
+
+// newCredentials returns the credentials of cfg: static keys, or the keys in
+// CredentialsFile, which are reloaded whenever the file changes
+func (cfg Config) newCredentials() (*credentials.Credentials, error) {
+	if cfg.CredentialsFile == "" {
+		return credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""), nil
+	}
+
+	provider := &fileCredentials{path: cfg.CredentialsFile}
+	creds := credentials.New(provider)
+	// Fail at startup instead of on the first request
+	if _, err := creds.Get(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// fileCredentials provides credentials from a YAML (or JSON) file with
+// access_key, secret_key and an optional session_token. It reports the
+// credentials as expired when the file's modification time or size changes,
+// so that tokens rotated by an external process (e.g. an STS refresher or a
+// Kubernetes secret) are picked up without restarting. Calls are serialized
+// by credentials.Credentials.
+type fileCredentials struct {
+	path    string
+	value   credentials.Value // Last loaded credentials
+	modTime time.Time         // Of the file when it was last loaded
+	size    int64
+	checked time.Time // Last time the file was checked for changes
+}
+
+type credentialsFile struct {
+	AccessKey    string `yaml:"access_key"`
+	SecretKey    string `yaml:"secret_key"`
+	SessionToken string `yaml:"session_token"`
+}
+
+// Retrieve loads the credentials from the file. Once loaded, a file that
+// cannot be read or parsed (e.g. while it is being rewritten) keeps the
+// previous credentials, and the file is loaded again on the next check.
+func (p *fileCredentials) Retrieve() (credentials.Value, error) {
+	value, err := p.load()
+	if err != nil {
+		if p.value.AccessKeyID != "" {
+			return p.value, nil
+		}
+		return credentials.Value{}, err
+	}
+	p.value = value
+	return value, nil
+}
+
+func (p *fileCredentials) load() (credentials.Value, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var file credentialsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return credentials.Value{}, fmt.Errorf("invalid credentials file %s: %w", p.path, err)
+	}
+	if file.AccessKey == "" || file.SecretKey == "" {
+		return credentials.Value{}, fmt.Errorf("credentials file %s must set access_key and secret_key", p.path)
+	}
+
+	p.modTime, p.size = info.ModTime(), info.Size()
+	p.checked = time.Now()
+	return credentials.Value{
+		AccessKeyID:     file.AccessKey,
+		SecretAccessKey: file.SecretKey,
+		SessionToken:    file.SessionToken,
+		SignerType:      credentials.SignatureV4,
+	}, nil
+}
+
+// IsExpired reports whether the file changed since it was loaded, checking at
+// most once per credentialsCheckInterval. A file that cannot be read keeps
+// the loaded credentials.
+func (p *fileCredentials) IsExpired() bool {
+	if time.Since(p.checked) < credentialsCheckInterval {
+		return false
+	}
+	p.checked = time.Now()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return false
+	}
+	return !info.ModTime().Equal(p.modTime) || info.Size() != p.size
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+var (
+	// ErrNotFound is returned by non-S3 clients when an object does not exist
+	ErrNotFound = errors.New("object not found")
+	// ErrNotImplemented is returned by clients that do not support an operation
+	ErrNotImplemented = errors.New("operation not implemented")
+	// ErrNotModified is returned by non-S3 clients when a conditional read
+	// matched the object's ETag
+	ErrNotModified = errors.New("object not modified")
+	// ErrPreconditionFailed is returned by non-S3 clients when a conditional
+	// upload was rejected
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Failure categories reported by ErrorCategory
+const (
+	CategoryAuth     = "auth"
+	CategoryNetwork  = "network"
+	CategoryNotFound = "not-found"
+	CategoryQuota    = "quota"
+	CategoryServer   = "server"
+	CategoryOther    = "other"
+)
+
+// errorResponse extracts the S3 error response wrapped in err, if any
+func errorResponse(err error) (minio.ErrorResponse, bool) {
+	var resp minio.ErrorResponse
+	if errors.As(err, &resp) {
+		return resp, true
+	}
+	return resp, false
+}
+
+// IsNotFound reports whether err indicates that the object does not exist
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrNotFound) {
+		return true
+	}
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	return resp.StatusCode == http.StatusNotFound || resp.Code == "NoSuchKey"
+}
+
+// IsAccessDenied reports whether err indicates that the credentials lack
+// permission for the request (HTTP 403)
+func IsAccessDenied(err error) bool {
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	return resp.StatusCode == http.StatusForbidden || resp.Code == "AccessDenied"
+}
+
+// IsNotModified reports whether err indicates that a conditional read was
+// answered with 304 Not Modified
+func IsNotModified(err error) bool {
+	if errors.Is(err, ErrNotModified) {
+		return true
+	}
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	return resp.StatusCode == http.StatusNotModified || resp.Code == "NotModified"
+}
+
+// IsPreconditionFailed reports whether err indicates that a conditional
+// request was rejected with 412 Precondition Failed
+func IsPreconditionFailed(err error) bool {
+	if errors.Is(err, ErrPreconditionFailed) {
+		return true
+	}
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	return resp.StatusCode == http.StatusPreconditionFailed || resp.Code == "PreconditionFailed"
+}
+
+// IsNotImplemented reports whether err indicates that the server does not
+// support the requested operation (e.g. multipart uploads on minimal S3 servers)
+func IsNotImplemented(err error) bool {
+	if errors.Is(err, ErrNotImplemented) {
+		return true
+	}
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	return resp.StatusCode == http.StatusNotImplemented ||
+		resp.StatusCode == http.StatusMethodNotAllowed ||
+		resp.Code == "NotImplemented" ||
+		resp.Code == "MethodNotAllowed"
+}
+
+// IsThrottled reports whether err indicates that the server is rate limiting
+// requests (HTTP 429 or an S3 SlowDown style error)
+func IsThrottled(err error) bool {
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	switch resp.Code {
+	case "SlowDown", "SlowDownRead", "Throttling", "ThrottlingException", "TooManyRequests",
+		"RequestLimitExceeded", "RequestThrottled":
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests
+}
+
+// IsObjectLocked reports whether err indicates that the destination refused to
+// overwrite an object protected by object lock (WORM retention or legal hold)
+func IsObjectLocked(err error) bool {
+	resp, ok := errorResponse(err)
+	if !ok {
+		return false
+	}
+	if resp.Code == "ObjectLocked" {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusBadRequest &&
+		resp.Code != "AccessDenied" && resp.Code != "InvalidRequest" {
+		return false
+	}
+
+	message := strings.ToLower(resp.Message)
+	return strings.Contains(message, "worm") ||
+		strings.Contains(message, "object lock") ||
+		strings.Contains(message, "retention") ||
+		strings.Contains(message, "legal hold")
+}
+
+// ErrorCategory classifies err into one of the failure categories so that
+// failures can be summarized by their nature
+func ErrorCategory(err error) string {
+	if err == nil {
+		return CategoryOther
+	}
+	if IsNotFound(err) {
+		return CategoryNotFound
+	}
+
+	if resp, ok := errorResponse(err); ok {
+		switch resp.Code {
+		case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken",
+			"InvalidToken", "AccountProblem", "AllAccessDisabled":
+			return CategoryAuth
+		case "NoSuchBucket", "NoSuchUpload":
+			return CategoryNotFound
+		case "QuotaExceeded", "XMinioAdminBucketQuotaExceeded", "XMinioStorageFull", "StorageFull",
+			"EntityTooLarge", "TooManyBuckets":
+			return CategoryQuota
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			return CategoryAuth
+		case resp.StatusCode == http.StatusInsufficientStorage || resp.StatusCode == http.StatusRequestEntityTooLarge:
+			return CategoryQuota
+		case resp.StatusCode >= http.StatusInternalServerError:
+			return CategoryServer
+		}
+		return CategoryOther
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded) {
+		return CategoryNetwork
+	}
+
+	message := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(message, "quota") || strings.Contains(message, "insufficient storage"):
+		return CategoryQuota
+	case strings.Contains(message, "connection") || strings.Contains(message, "timeout") ||
+		strings.Contains(message, "no such host") || strings.Contains(message, "network") ||
+		strings.Contains(message, "eof"):
+		return CategoryNetwork
+	}
+	return CategoryOther
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7/pkg/s3utils"
+)
+
+// HTTPSinkClient is a write-only destination that uploads each object with an
+// HTTP POST to <endpoint>/<bucket>/<key>. It shows how a non-S3 system can be
+// plugged in as a destination; operations it cannot support return
+// ErrNotImplemented, and the migration falls back accordingly (e.g. multipart
+// uploads degrade to a single PutObject).
+type HTTPSinkClient struct {
+	endpoint *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewHTTPSinkClient creates a new HTTP sink client. Endpoint must be a full
+// http(s) URL; access/secret keys, if set, are sent as basic auth credentials.
+func NewHTTPSinkClient(cfg Config) (*HTTPSinkClient, error) {
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, fmt.Errorf("invalid endpoint: HTTP sink requires an http(s) URL, got %q", cfg.Endpoint)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Minute}
+	tlsCfg, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := cfg.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil || proxy != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if tlsCfg != nil {
+			transport.TLSClientConfig = tlsCfg
+		}
+		if proxy != nil {
+			transport.Proxy = proxy
+		}
+		client.Transport = transport
+	}
+
+	return &HTTPSinkClient{
+		endpoint: endpoint,
+		username: cfg.AccessKey,
+		password: cfg.SecretKey,
+		client:   client,
+	}, nil
+}
+
+// objectURL returns the upload URL for an object. The key is escaped like
+// S3 paths, including "+" which some servers decode as a space, so that the
+// sink receives it byte for byte.
+func (c *HTTPSinkClient) objectURL(bucket, key string) string {
+	u := *c.endpoint
+	base := strings.TrimSuffix(u.EscapedPath(), "/")
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+	u.RawPath = base + "/" + s3utils.EncodePath(bucket) + "/" + s3utils.EncodePath(key)
+	return u.String()
+}
+
+// PutObject uploads an object with an HTTP POST. The ETag response header,
+// if the sink sets one, is returned.
+func (c *HTTPSinkClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.objectURL(bucket, key), reader)
+	if err != nil {
+		return "", err
+	}
+
+	req.ContentLength = size
+	if opts.ContentType != "" {
+		req.Header.Set("Content-Type", opts.ContentType)
+	}
+	// The body is sent as is, still encoded
+	if opts.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", opts.ContentEncoding)
+	}
+	for k, v := range opts.Metadata {
+		req.Header.Set("X-Object-Meta-"+k, v)
+	}
+	if opts.IfNoneMatch != "" {
+		req.Header.Set("If-None-Match", quoteETag(opts.IfNoneMatch))
+	}
+	if opts.IfMatch != "" {
+		req.Header.Set("If-Match", quoteETag(opts.IfMatch))
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.Header.Get("ETag"), nil
+	case resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusMethodNotAllowed:
+		return "", fmt.Errorf("upload %s: %s: %w", key, resp.Status, ErrNotImplemented)
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return "", fmt.Errorf("upload %s: %s: %w", key, resp.Status, ErrPreconditionFailed)
+	default:
+		return "", fmt.Errorf("upload %s: unexpected status %s", key, resp.Status)
+	}
+}
+
+// GetObject is not supported by the sink
+func (c *HTTPSinkClient) GetObject(ctx context.Context, bucket, key string, opts GetOptions) (Object, error) {
+	return nil, ErrNotImplemented
+}
+
+// GetObjectRange is not supported by the sink
+func (c *HTTPSinkClient) GetObjectRange(ctx context.Context, bucket, key string, offset, length int64, etag string) (Object, error) {
+	return nil, ErrNotImplemented
+}
+
+// HeadObject is not supported by the sink; existence checks always miss
+func (c *HTTPSinkClient) HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	return ObjectInfo{}, ErrNotImplemented
+}
+
+// ListObjects is not supported by the sink
+func (c *HTTPSinkClient) ListObjects(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error) {
+	objCh := make(chan ObjectInfo)
+	errCh := make(chan error, 1)
+	close(objCh)
+	errCh <- ErrNotImplemented
+	close(errCh)
+	return objCh, errCh
+}
+
+// ListObjectsPage is not supported by the sink
+func (c *HTTPSinkClient) ListObjectsPage(ctx context.Context, bucket, prefix, token string) (ListPage, error) {
+	return ListPage{}, ErrNotImplemented
+}
+
+// ListPrefixes is not supported by the sink
+func (c *HTTPSinkClient) ListPrefixes(ctx context.Context, bucket, prefix string) ([]string, []ObjectInfo, error) {
+	return nil, nil, ErrNotImplemented
+}
+
+// RemoveObject is not supported by the sink
+func (c *HTTPSinkClient) RemoveObject(ctx context.Context, bucket, key string) error {
+	return ErrNotImplemented
+}
+
+// UpdateMetadata is not supported by the sink
+func (c *HTTPSinkClient) UpdateMetadata(ctx context.Context, bucket, key, etag string, opts PutOptions) error {
+	return ErrNotImplemented
+}
+
+// GetObjectACL is not supported by the write-only sink
+func (c *HTTPSinkClient) GetObjectACL(ctx context.Context, bucket, key string) (ACL, error) {
+	return ACL{}, ErrNotImplemented
+}
+
+// GetObjectTags is not supported by the write-only sink
+func (c *HTTPSinkClient) GetObjectTags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	return nil, ErrNotImplemented
+}
+
+// PutObjectTags is not supported by the sink
+func (c *HTTPSinkClient) PutObjectTags(ctx context.Context, bucket, key string, tags map[string]string) error {
+	return ErrNotImplemented
+}
+
+// BucketExists is not supported by the sink, which has no buckets
+func (c *HTTPSinkClient) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	return false, ErrNotImplemented
+}
+
+// ListenBucketNotification is not supported by the sink
+func (c *HTTPSinkClient) ListenBucketNotification(ctx context.Context, bucket, prefix string, events []string) (<-chan Event, <-chan error) {
+	eventCh := make(chan Event)
+	errCh := make(chan error, 1)
+	close(eventCh)
+	errCh <- ErrNotImplemented
+	close(errCh)
+	return eventCh, errCh
+}
+
+// NewMultipartUpload is not supported by the sink; callers fall back to PutObject
+func (c *HTTPSinkClient) NewMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
+	return "", ErrNotImplemented
+}
+
+// UploadPart is not supported by the sink
+func (c *HTTPSinkClient) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	return "", ErrNotImplemented
+}
+
+// CompleteMultipartUpload is not supported by the sink
+func (c *HTTPSinkClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) (string, error) {
+	return "", ErrNotImplemented
+}
+
+// AbortMultipartUpload is not supported by the sink
+func (c *HTTPSinkClient) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	return ErrNotImplemented
+}
+
+// quoteETag formats an ETag for a conditional header; the "*" wildcard is
+// sent as is
+func quoteETag(etag string) string {
+	etag = strings.Trim(etag, `"`)
+	if etag == "*" {
+		return etag
+	}
+	return `"` + etag + `"`
+}
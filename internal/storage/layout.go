@@ -0,0 +1,13 @@
+package storage
+
+import "context"
+
+// LayoutLister is implemented by clients that can list objects in the order
+// the backend stores them on disk rather than in key order. Reading a cold
+// source in layout order turns random reads into mostly sequential ones,
+// which helps when migrating doubles as warming a cache. Clients that cannot
+// are detected with a type assertion; none of the S3 clients can, since the
+// S3 API only lists in key order.
+type LayoutLister interface {
+	ListObjectsByLayout(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error)
+}
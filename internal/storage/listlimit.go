@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// listLimiter spaces out list requests of a client, across all of its
+// concurrent listings, so that no more than a given number are sent per
+// second. Providers that charge per LIST request or rate-limit listings are
+// then enumerated within a request budget.
+type listLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Earliest time of the next request
+}
+
+// newListLimiter returns nil, which does not limit, when perSecond is not
+// positive
+func newListLimiter(perSecond float64) *listLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &listLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next list request may be sent or ctx is done
+func (l *listLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
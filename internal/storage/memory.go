@@ -0,0 +1,588 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryClient is an in-memory Client backed by maps, for exercising the
+// migration logic without an S3 server. Buckets must be created with
+// MakeBucket. ETags follow S3: the MD5 of the data for single uploads, and
+// the MD5 of the part MD5s with a "-<parts>" suffix for multipart uploads.
+// Bucket notifications are not supported.
+type MemoryClient struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]*memoryObject
+	uploads map[string]*memoryUpload
+	nextID  int
+	nextSeq int64
+}
+
+// memoryObject is a stored object
+type memoryObject struct {
+	data []byte
+	info ObjectInfo
+	acl  ACL
+	tags map[string]string
+	seq  int64 // Write order, reported as the layout order
+}
+
+// memoryUpload is a multipart upload in progress
+type memoryUpload struct {
+	bucket string
+	key    string
+	opts   PutOptions
+	parts  map[int][]byte
+}
+
+// NewMemoryClient creates an empty in-memory client with the given buckets
+func NewMemoryClient(buckets ...string) *MemoryClient {
+	c := &MemoryClient{
+		buckets: make(map[string]map[string]*memoryObject),
+		uploads: make(map[string]*memoryUpload),
+	}
+	for _, bucket := range buckets {
+		c.MakeBucket(bucket)
+	}
+	return c
+}
+
+// MakeBucket creates bucket if it does not exist yet
+func (c *MemoryClient) MakeBucket(bucket string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.buckets[bucket]; !ok {
+		c.buckets[bucket] = make(map[string]*memoryObject)
+	}
+}
+
+// SetObjectACL replaces the ACL returned by GetObjectACL for an object
+func (c *MemoryClient) SetObjectACL(bucket, key string, acl ACL) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return err
+	}
+	obj.acl = acl
+	return nil
+}
+
+// Data returns a copy of an object's data
+func (c *MemoryClient) Data(bucket, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Clone(obj.data), nil
+}
+
+// objects returns the objects of bucket. The caller must hold c.mu.
+func (c *MemoryClient) objects(bucket string) (map[string]*memoryObject, error) {
+	objects, ok := c.buckets[bucket]
+	if !ok {
+		return nil, fmt.Errorf("bucket %s: %w", bucket, ErrNotFound)
+	}
+	return objects, nil
+}
+
+// object returns a stored object. The caller must hold c.mu.
+func (c *MemoryClient) object(bucket, key string) (*memoryObject, error) {
+	objects, err := c.objects(bucket)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := objects[key]
+	if !ok {
+		return nil, fmt.Errorf("%s/%s: %w", bucket, key, ErrNotFound)
+	}
+	return obj, nil
+}
+
+// store saves data as an object. The caller must hold c.mu.
+func (c *MemoryClient) store(bucket, key string, data []byte, etag string, opts PutOptions) error {
+	objects, err := c.objects(bucket)
+	if err != nil {
+		return err
+	}
+
+	objects[key] = &memoryObject{
+		data: data,
+		info: ObjectInfo{
+			Key:             key,
+			Size:            int64(len(data)),
+			ETag:            etag,
+			LastModified:    time.Now().UTC(),
+			ContentType:     opts.ContentType,
+			ContentEncoding: opts.ContentEncoding,
+			Metadata:        cloneMetadata(opts.Metadata),
+		},
+		acl:  ACL{Grants: append([]Grant(nil), opts.Grants...)},
+		tags: cloneMetadata(opts.Tags),
+		seq:  c.nextSeq,
+	}
+	c.nextSeq++
+	return nil
+}
+
+// GetObject reads a whole object
+func (c *MemoryClient) GetObject(ctx context.Context, bucket, key string, opts GetOptions) (Object, error) {
+	if opts.IfNoneMatch != "" {
+		c.mu.Lock()
+		obj, err := c.object(bucket, key)
+		if err == nil && strings.Trim(opts.IfNoneMatch, `"`) == obj.info.ETag {
+			err = fmt.Errorf("%s/%s: %w", bucket, key, ErrNotModified)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.GetObjectRange(ctx, bucket, key, 0, 0, "")
+}
+
+// GetObjectRange reads length bytes from offset, or up to the end when length <= 0
+func (c *MemoryClient) GetObjectRange(ctx context.Context, bucket, key string, offset, length int64, etag string) (Object, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" && strings.Trim(etag, `"`) != obj.info.ETag {
+		return nil, fmt.Errorf("%s/%s: etag %s does not match %s", bucket, key, etag, obj.info.ETag)
+	}
+
+	size := int64(len(obj.data))
+	if offset < 0 || offset > size {
+		return nil, fmt.Errorf("%s/%s: offset %d outside object of %d bytes", bucket, key, offset, size)
+	}
+	end := size
+	if length > 0 && offset+length < size {
+		end = offset + length
+	}
+
+	return &memoryReader{Reader: bytes.NewReader(obj.data[offset:end]), info: cloneInfo(obj.info)}, nil
+}
+
+// PutObject stores an object read from reader
+func (c *MemoryClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) (string, error) {
+	data, err := readExactly(reader, size)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum(data)
+	etag := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if opts.IfNoneMatch != "" || opts.IfMatch != "" {
+		etag := ""
+		obj, err := c.object(bucket, key)
+		if err == nil {
+			etag = obj.info.ETag
+		}
+		ifNoneMatch := strings.Trim(opts.IfNoneMatch, `"`)
+		if (ifNoneMatch == "*" && err == nil) || (ifNoneMatch != "" && ifNoneMatch == etag) ||
+			(opts.IfMatch != "" && strings.Trim(opts.IfMatch, `"`) != etag) {
+			return "", fmt.Errorf("%s/%s: %w", bucket, key, ErrPreconditionFailed)
+		}
+	}
+	if err := c.store(bucket, key, data, etag, opts); err != nil {
+		return "", err
+	}
+	return etag, nil
+}
+
+// HeadObject returns the metadata of an object
+func (c *MemoryClient) HeadObject(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return cloneInfo(obj.info), nil
+}
+
+// GetObjectACL returns the ACL of an object; objects start with no grants
+func (c *MemoryClient) GetObjectACL(ctx context.Context, bucket, key string) (ACL, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return ACL{}, err
+	}
+	return ACL{Owner: obj.acl.Owner, Grants: append([]Grant(nil), obj.acl.Grants...)}, nil
+}
+
+// GetObjectTags returns the tag set of an object
+func (c *MemoryClient) GetObjectTags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	tags := cloneMetadata(obj.tags)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return tags, nil
+}
+
+// PutObjectTags replaces the tag set of an object
+func (c *MemoryClient) PutObjectTags(ctx context.Context, bucket, key string, tags map[string]string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return err
+	}
+	obj.tags = cloneMetadata(tags)
+	return nil
+}
+
+// ListObjects lists the objects under prefix in key order. The listing is a
+// snapshot taken when it starts. Like S3 listings, it leaves out user metadata.
+func (c *MemoryClient) ListObjects(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error) {
+	var infos []ObjectInfo
+	var err error
+	if opts.NonRecursive {
+		_, infos, err = c.ListPrefixes(ctx, bucket, prefix)
+	} else {
+		infos, err = c.list(bucket, prefix)
+	}
+	return streamInfos(ctx, infos, err)
+}
+
+// ListObjectsByLayout lists the objects under prefix in the order they were
+// written, which is how an append-only store lays them out
+func (c *MemoryClient) ListObjectsByLayout(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, err := c.objects(bucket)
+	if err != nil {
+		return streamInfos(ctx, nil, err)
+	}
+
+	var found []*memoryObject
+	for key, obj := range objects {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if opts.NonRecursive && strings.Contains(strings.TrimPrefix(key, prefix), "/") {
+			continue
+		}
+		found = append(found, obj)
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].seq < found[j].seq })
+
+	infos := make([]ObjectInfo, len(found))
+	for i, obj := range found {
+		infos[i] = cloneInfo(obj.info)
+	}
+	return streamInfos(ctx, infos, nil)
+}
+
+// streamInfos sends a listing snapshot, or err, on channels like those of
+// ListObjects
+func streamInfos(ctx context.Context, infos []ObjectInfo, err error) (<-chan ObjectInfo, <-chan error) {
+	objCh := make(chan ObjectInfo)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(objCh)
+		defer close(errCh)
+
+		if err != nil {
+			errCh <- err
+			return
+		}
+		for _, info := range infos {
+			info.Metadata = nil
+			select {
+			case objCh <- info:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return objCh, errCh
+}
+
+// list returns the objects under prefix sorted by key
+func (c *MemoryClient) list(bucket, prefix string) ([]ObjectInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, err := c.objects(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []ObjectInfo
+	for key, obj := range objects {
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, cloneInfo(obj.info))
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+// memoryPageSize is the number of objects in a page of ListObjectsPage
+const memoryPageSize = 1000
+
+// ListObjectsPage lists a page of objects under prefix. The continuation
+// token is the last key of the previous page.
+func (c *MemoryClient) ListObjectsPage(ctx context.Context, bucket, prefix, token string) (ListPage, error) {
+	infos, err := c.list(bucket, prefix)
+	if err != nil {
+		return ListPage{}, err
+	}
+
+	start := sort.Search(len(infos), func(i int) bool { return infos[i].Key > token })
+	end := min(start+memoryPageSize, len(infos))
+
+	page := ListPage{Objects: infos[start:end]}
+	for i := range page.Objects {
+		page.Objects[i].Metadata = nil
+	}
+	if end < len(infos) {
+		page.NextToken = infos[end-1].Key
+	}
+	return page, nil
+}
+
+// ListPrefixes lists the common prefixes and objects one level below prefix
+func (c *MemoryClient) ListPrefixes(ctx context.Context, bucket, prefix string) ([]string, []ObjectInfo, error) {
+	infos, err := c.list(bucket, prefix)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var prefixes []string
+	var objects []ObjectInfo
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		rest := strings.TrimPrefix(info.Key, prefix)
+		if i := strings.Index(rest, "/"); i >= 0 {
+			common := prefix + rest[:i+1]
+			if !seen[common] {
+				seen[common] = true
+				prefixes = append(prefixes, common)
+			}
+			continue
+		}
+		info.Metadata = nil
+		objects = append(objects, info)
+	}
+	return prefixes, objects, nil
+}
+
+// RemoveObject deletes an object; deleting a missing object succeeds as on S3
+func (c *MemoryClient) RemoveObject(ctx context.Context, bucket, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	objects, err := c.objects(bucket)
+	if err != nil {
+		return err
+	}
+	delete(objects, key)
+	return nil
+}
+
+// UpdateMetadata replaces the content type, encoding, user metadata and ACL
+// of an object, keeping its data and ETag
+func (c *MemoryClient) UpdateMetadata(ctx context.Context, bucket, key, etag string, opts PutOptions) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	obj, err := c.object(bucket, key)
+	if err != nil {
+		return err
+	}
+	if etag != "" && strings.Trim(etag, `"`) != obj.info.ETag {
+		return fmt.Errorf("%s/%s: etag %s does not match %s", bucket, key, etag, obj.info.ETag)
+	}
+
+	obj.info.ContentType = opts.ContentType
+	obj.info.ContentEncoding = opts.ContentEncoding
+	obj.info.Metadata = cloneMetadata(opts.Metadata)
+	obj.acl = ACL{Grants: append([]Grant(nil), opts.Grants...)}
+	return nil
+}
+
+// BucketExists reports whether bucket was created
+func (c *MemoryClient) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.buckets[bucket]
+	return ok, nil
+}
+
+// ListenBucketNotification is not supported by the in-memory client
+func (c *MemoryClient) ListenBucketNotification(ctx context.Context, bucket, prefix string, events []string) (<-chan Event, <-chan error) {
+	eventCh := make(chan Event)
+	errCh := make(chan error, 1)
+	close(eventCh)
+	errCh <- ErrNotImplemented
+	close(errCh)
+	return eventCh, errCh
+}
+
+// NewMultipartUpload starts a multipart upload
+func (c *MemoryClient) NewMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.objects(bucket); err != nil {
+		return "", err
+	}
+
+	c.nextID++
+	uploadID := "upload-" + strconv.Itoa(c.nextID)
+	c.uploads[uploadID] = &memoryUpload{bucket: bucket, key: key, opts: opts, parts: make(map[int][]byte)}
+	return uploadID, nil
+}
+
+// UploadPart stores a part of a multipart upload, replacing an earlier upload
+// of the same part number
+func (c *MemoryClient) UploadPart(ctx context.Context, bucket, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	data, err := readExactly(reader, size)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upload, err := c.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+	upload.parts[partNumber] = data
+
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CompleteMultipartUpload assembles the listed parts into the object
+func (c *MemoryClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	upload, err := c.upload(bucket, key, uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	sums := md5.New()
+	for i, part := range parts {
+		if i > 0 && part.PartNumber <= parts[i-1].PartNumber {
+			return "", fmt.Errorf("upload %s: parts must be in ascending order", uploadID)
+		}
+		partData, ok := upload.parts[part.PartNumber]
+		if !ok {
+			return "", fmt.Errorf("upload %s: part %d was not uploaded", uploadID, part.PartNumber)
+		}
+		sum := md5.Sum(partData)
+		if etag := hex.EncodeToString(sum[:]); strings.Trim(part.ETag, `"`) != etag {
+			return "", fmt.Errorf("upload %s: part %d etag %s does not match %s", uploadID, part.PartNumber, part.ETag, etag)
+		}
+		data = append(data, partData...)
+		sums.Write(sum[:])
+	}
+
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(sums.Sum(nil)), len(parts))
+	if err := c.store(bucket, key, data, etag, upload.opts); err != nil {
+		return "", err
+	}
+	delete(c.uploads, uploadID)
+	return etag, nil
+}
+
+// AbortMultipartUpload discards a multipart upload and its parts
+func (c *MemoryClient) AbortMultipartUpload(ctx context.Context, bucket, key, uploadID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.upload(bucket, key, uploadID); err != nil {
+		return err
+	}
+	delete(c.uploads, uploadID)
+	return nil
+}
+
+// upload returns a multipart upload of bucket/key. The caller must hold c.mu.
+func (c *MemoryClient) upload(bucket, key, uploadID string) (*memoryUpload, error) {
+	upload, ok := c.uploads[uploadID]
+	if !ok || upload.bucket != bucket || upload.key != key {
+		return nil, fmt.Errorf("upload %s of %s/%s: %w", uploadID, bucket, key, ErrNotFound)
+	}
+	return upload, nil
+}
+
+// memoryReader is an object stream over stored data
+type memoryReader struct {
+	*bytes.Reader
+	info ObjectInfo
+}
+
+func (r *memoryReader) Close() error {
+	return nil
+}
+
+func (r *memoryReader) Stat() (ObjectInfo, error) {
+	return r.info, nil
+}
+
+// readExactly reads size bytes from reader, or everything when size < 0
+func readExactly(reader io.Reader, size int64) ([]byte, error) {
+	if size < 0 {
+		return io.ReadAll(reader)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, fmt.Errorf("short upload body: %w", err)
+	}
+	return data, nil
+}
+
+func cloneInfo(info ObjectInfo) ObjectInfo {
+	info.Metadata = cloneMetadata(info.Metadata)
+	return info
+}
+
+func cloneMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		clone[k] = v
+	}
+	return clone
+}
@@ -4,16 +4,23 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/tags"
 )
 
 // MinIOClient implements the Client interface using minio-go
 type MinIOClient struct {
-	client *minio.Client
+	client     *minio.Client
+	creds      *credentials.Credentials
+	httpClient *http.Client // For admin API requests, which minio-go does not cover
+	listRate   *listLimiter // Spaces out list requests; nil is unlimited
 }
 
 // NewMinIOClient creates a new MinIO client
@@ -24,15 +31,61 @@ func NewMinIOClient(cfg Config) (*MinIOClient, error) {
 		return nil, fmt.Errorf("invalid endpoint: %w", err)
 	}
 
-	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+	creds, err := cfg.newCredentials()
+	if err != nil {
+		return nil, err
+	}
+	opts := &minio.Options{
+		Creds:  creds,
 		Secure: cfg.Secure,
-	})
+	}
+
+	tlsCfg, err := cfg.tlsConfig()
 	if err != nil {
 		return nil, err
 	}
+	proxy, err := cfg.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil || proxy != nil {
+		transport, err := minio.DefaultTransport(cfg.Secure)
+		if err != nil {
+			return nil, err
+		}
+		if tlsCfg != nil {
+			transport.TLSClientConfig = tlsCfg
+		}
+		if proxy != nil {
+			transport.Proxy = proxy
+		}
+		opts.Transport = transport
+	}
+
+	client, err := minio.New(endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := opts.Transport
+	if transport == nil {
+		if transport, err = minio.DefaultTransport(cfg.Secure); err != nil {
+			return nil, err
+		}
+	}
 
-	return &MinIOClient{client: client}, nil
+	return &MinIOClient{
+		client:     client,
+		creds:      creds,
+		httpClient: &http.Client{Transport: transport},
+		listRate:   newListLimiter(cfg.ListRateLimit),
+	}, nil
+}
+
+// ValidateEndpoint reports whether endpoint can be used by NewMinIOClient
+func ValidateEndpoint(endpoint string) error {
+	_, err := cleanEndpoint(endpoint)
+	return err
 }
 
 // cleanEndpoint removes protocol and path from endpoint URL to get host:port format
@@ -45,6 +98,9 @@ func cleanEndpoint(endpoint string) (string, error) {
 	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
 		// Check if it's already in host:port format
 		if strings.Contains(endpoint, "/") {
+			if host, bucket := EndpointBucket(endpoint); bucket != "" {
+				return "", fmt.Errorf("endpoint contains path but no protocol; %s", bucketPathHint(host, bucket))
+			}
 			return "", fmt.Errorf("endpoint contains path but no protocol")
 		}
 		return endpoint, nil
@@ -58,6 +114,10 @@ func cleanEndpoint(endpoint string) (string, error) {
 
 	// Check if path is not empty (indicating a full URL with path)
 	if parsedURL.Path != "" && parsedURL.Path != "/" {
+		// A common mistake is appending the bucket, as in path-style URLs
+		if host, bucket := EndpointBucket(endpoint); bucket != "" {
+			return "", fmt.Errorf("endpoint URL cannot have paths, only host:port is allowed (got path: %s); %s", parsedURL.Path, bucketPathHint(host, bucket))
+		}
 		return "", fmt.Errorf("endpoint URL cannot have paths, only host:port is allowed (got path: %s)", parsedURL.Path)
 	}
 
@@ -65,9 +125,80 @@ func cleanEndpoint(endpoint string) (string, error) {
 	return parsedURL.Host, nil
 }
 
-// GetObject retrieves an object
-func (c *MinIOClient) GetObject(ctx context.Context, bucket, key string) (Object, error) {
-	obj, err := c.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+// bucketNamePattern matches S3 bucket names: 3 to 63 lowercase letters,
+// digits, dots and hyphens, starting and ending with a letter or digit
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// EndpointBucket splits an endpoint URL whose path is a single bucket name,
+// such as https://minio.example.com/mybucket, into the endpoint without the
+// path and the bucket. Endpoints without a protocol, such as
+// minio.example.com:9000/mybucket, are split the same way. Other endpoints are
+// returned unchanged with an empty bucket.
+func EndpointBucket(endpoint string) (string, string) {
+	hasScheme := strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://")
+	raw := endpoint
+	if !hasScheme {
+		raw = "http://" + endpoint
+	}
+
+	parsedURL, err := url.Parse(raw)
+	if err != nil || parsedURL.Host == "" || parsedURL.RawQuery != "" {
+		return endpoint, ""
+	}
+
+	bucket := strings.Trim(parsedURL.Path, "/")
+	if !bucketNamePattern.MatchString(bucket) {
+		return endpoint, ""
+	}
+	if !hasScheme {
+		return parsedURL.Host, bucket
+	}
+	parsedURL.Path, parsedURL.RawPath = "", ""
+	return parsedURL.String(), bucket
+}
+
+// bucketPathHint explains how to fix an endpoint that includes a bucket
+func bucketPathHint(endpoint, bucket string) string {
+	return fmt.Sprintf("%q looks like a bucket name: set it with --bucket (or dst_bucket of a job for the destination) and use %s as the endpoint, or pass --lenient-endpoint to move it automatically", bucket, endpoint)
+}
+
+// GetObject retrieves an object. The request is only sent on the first Read
+// or Stat, so a not-modified response is reported there.
+func (c *MinIOClient) GetObject(ctx context.Context, bucket, key string, opts GetOptions) (Object, error) {
+	getOpts := minio.GetObjectOptions{}
+	if opts.IfNoneMatch != "" {
+		if err := getOpts.SetMatchETagExcept(strings.Trim(opts.IfNoneMatch, `"`)); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, err := c.client.GetObject(ctx, bucket, key, getOpts)
+	if err != nil {
+		return nil, err
+	}
+	return &minioObject{obj}, nil
+}
+
+// GetObjectRange retrieves length bytes of an object starting at offset
+func (c *MinIOClient) GetObjectRange(ctx context.Context, bucket, key string, offset, length int64, etag string) (Object, error) {
+	opts := minio.GetObjectOptions{}
+	if length > 0 {
+		if err := opts.SetRange(offset, offset+length-1); err != nil {
+			return nil, err
+		}
+	} else if offset > 0 {
+		// SetRange(0, 0) would request only the first byte
+		if err := opts.SetRange(offset, 0); err != nil {
+			return nil, err
+		}
+	}
+	if etag != "" {
+		if err := opts.SetMatchETag(strings.Trim(etag, `"`)); err != nil {
+			return nil, err
+		}
+	}
+
+	obj, err := c.client.GetObject(ctx, bucket, key, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -75,14 +206,70 @@ func (c *MinIOClient) GetObject(ctx context.Context, bucket, key string) (Object
 }
 
 // PutObject uploads an object
-func (c *MinIOClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) error {
+func (c *MinIOClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts PutOptions) (string, error) {
 	putOpts := minio.PutObjectOptions{
-		ContentType:  opts.ContentType,
-		UserMetadata: opts.Metadata,
+		ContentType:      opts.ContentType,
+		ContentEncoding:  opts.ContentEncoding,
+		UserMetadata:     withGrants(opts.Metadata, opts.Grants),
+		UserTags:         opts.Tags,
+		DisableMultipart: opts.DisableMultipart,
+	}
+	// minio-go sends both values quoted, including the "*" wildcard
+	if opts.IfNoneMatch != "" {
+		putOpts.SetMatchETagExcept(strings.Trim(opts.IfNoneMatch, `"`))
+	}
+	if opts.IfMatch != "" {
+		putOpts.SetMatchETag(strings.Trim(opts.IfMatch, `"`))
 	}
 
-	_, err := c.client.PutObject(ctx, bucket, key, reader, size, putOpts)
-	return err
+	info, err := c.client.PutObject(ctx, bucket, key, reader, size, putOpts)
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+// GetObjectACL gets the owner and grants of an object. Grantees with a URI
+// are groups and grantees with an ID canonical users; S3 reports email
+// grantees as the canonical user they resolve to.
+func (c *MinIOClient) GetObjectACL(ctx context.Context, bucket, key string) (ACL, error) {
+	info, err := c.client.GetObjectACL(ctx, bucket, key)
+	if err != nil {
+		return ACL{}, err
+	}
+
+	acl := ACL{Owner: info.Owner.ID}
+	for _, g := range info.Grant {
+		grant := Grant{Permission: g.Permission}
+		switch {
+		case g.Grantee.URI != "":
+			grant.GranteeType, grant.Grantee = GranteeGroup, g.Grantee.URI
+		case g.Grantee.ID != "":
+			grant.GranteeType, grant.Grantee = GranteeCanonicalUser, g.Grantee.ID
+		default:
+			grant.Grantee = g.Grantee.DisplayName
+		}
+		acl.Grants = append(acl.Grants, grant)
+	}
+	return acl, nil
+}
+
+// GetObjectTags gets the tag set of an object
+func (c *MinIOClient) GetObjectTags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	objectTags, err := c.client.GetObjectTagging(ctx, bucket, key, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return objectTags.ToMap(), nil
+}
+
+// PutObjectTags replaces the tag set of an object
+func (c *MinIOClient) PutObjectTags(ctx context.Context, bucket, key string, objectTags map[string]string) error {
+	t, err := tags.NewTags(objectTags, true)
+	if err != nil {
+		return fmt.Errorf("invalid object tags: %w", err)
+	}
+	return c.client.PutObjectTagging(ctx, bucket, key, t, minio.PutObjectTaggingOptions{})
 }
 
 // HeadObject gets object metadata
@@ -93,55 +280,261 @@ func (c *MinIOClient) HeadObject(ctx context.Context, bucket, key string) (Objec
 	}
 
 	return ObjectInfo{
-		Key:          info.Key,
-		Size:         info.Size,
-		ETag:         info.ETag,
-		LastModified: info.LastModified,
-		ContentType:  info.ContentType, // Add ContentType field
-		Metadata:     info.UserMetadata,
+		Key:             info.Key,
+		Size:            info.Size,
+		ETag:            info.ETag,
+		LastModified:    info.LastModified,
+		ContentType:     info.ContentType, // Add ContentType field
+		ContentEncoding: info.Metadata.Get("Content-Encoding"),
+		Metadata:        info.UserMetadata,
+		Expiration:      info.Expiration,
 	}, nil
 }
 
-// ListObjects lists objects with prefix
-func (c *MinIOClient) ListObjects(ctx context.Context, bucket, prefix string) (<-chan ObjectInfo, <-chan error) {
+// Listing page sizes and backoff. Pages shrink while the source throttles
+// ListObjects and grow back after a run of healthy pages.
+const (
+	maxListPageSize        = 1000
+	minListPageSize        = 50
+	listGrowAfterPages     = 10
+	listThrottleBackoff    = time.Second
+	maxListThrottleBackoff = 30 * time.Second
+)
+
+// ListObjects lists objects with prefix one ListObjectsV2 page at a time.
+// Throttled list requests are retried with backoff from the same continuation
+// token, so aggressive rate limits slow the listing down instead of aborting
+// it. Pages shrink while the source throttles and grow back after a run of
+// healthy pages.
+func (c *MinIOClient) ListObjects(ctx context.Context, bucket, prefix string, opts ListOptions) (<-chan ObjectInfo, <-chan error) {
 	objCh := make(chan ObjectInfo)
 	errCh := make(chan error, 1)
 
+	delimiter := ""
+	if opts.NonRecursive {
+		delimiter = "/"
+	}
+
 	go func() {
 		defer close(objCh)
 		defer close(errCh)
 
-		for obj := range c.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{
-			Prefix:    prefix,
-			Recursive: true,
-		}) {
-			if obj.Err != nil {
-				errCh <- obj.Err
+		pageSize := maxListPageSize
+		healthy := 0
+		token := ""
+		for {
+			result, err := c.listPage(ctx, bucket, prefix, token, delimiter, pageSize, func() {
+				pageSize = max(pageSize/2, minListPageSize)
+				healthy = 0
+			})
+			if err != nil {
+				errCh <- err
 				return
 			}
 
-			select {
-			case objCh <- ObjectInfo{
-				Key:          obj.Key,
-				Size:         obj.Size,
-				ETag:         obj.ETag,
-				LastModified: obj.LastModified,
-				ContentType:  obj.ContentType, // Add ContentType field
-			}:
-			case <-ctx.Done():
+			for _, obj := range result.Contents {
+				select {
+				case objCh <- listedObject(obj):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if !result.IsTruncated {
 				return
 			}
+			token = result.NextContinuationToken
+
+			if pageSize < maxListPageSize {
+				if healthy++; healthy == listGrowAfterPages {
+					pageSize = min(pageSize*2, maxListPageSize)
+					healthy = 0
+				}
+			}
 		}
 	}()
 
 	return objCh, errCh
 }
 
+// ListObjectsPage lists one page with ListObjectsV2 from the continuation
+// token. Like ListObjects, a throttled request is retried with backoff.
+func (c *MinIOClient) ListObjectsPage(ctx context.Context, bucket, prefix, token string) (ListPage, error) {
+	result, err := c.listPage(ctx, bucket, prefix, token, "", maxListPageSize, nil)
+	if err != nil {
+		return ListPage{}, err
+	}
+
+	page := ListPage{Objects: make([]ObjectInfo, 0, len(result.Contents))}
+	for _, obj := range result.Contents {
+		page.Objects = append(page.Objects, listedObject(obj))
+	}
+	if result.IsTruncated {
+		page.NextToken = result.NextContinuationToken
+	}
+	return page, nil
+}
+
+// listPage requests one ListObjectsV2 page, waiting for the list rate limit
+// before every request. Throttled requests are retried with backoff, calling
+// onThrottled (if set) before each retry.
+func (c *MinIOClient) listPage(ctx context.Context, bucket, prefix, token, delimiter string, pageSize int, onThrottled func()) (minio.ListBucketV2Result, error) {
+	core := &minio.Core{Client: c.client}
+	backoff := listThrottleBackoff
+	for {
+		if err := c.listRate.Wait(ctx); err != nil {
+			return minio.ListBucketV2Result{}, err
+		}
+
+		result, err := core.ListObjectsV2(bucket, prefix, "", token, delimiter, pageSize)
+		if err == nil {
+			return result, nil
+		}
+		if !IsThrottled(err) || ctx.Err() != nil {
+			return minio.ListBucketV2Result{}, err
+		}
+
+		if onThrottled != nil {
+			onThrottled()
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return minio.ListBucketV2Result{}, ctx.Err()
+		}
+		backoff = min(backoff*2, maxListThrottleBackoff)
+	}
+}
+
+// listedObject converts an object of a listing page
+func listedObject(obj minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          obj.Key,
+		Size:         obj.Size,
+		ETag:         strings.Trim(obj.ETag, `"`),
+		LastModified: obj.LastModified,
+		ContentType:  obj.ContentType,
+	}
+}
+
+// ListPrefixes lists objects and common prefixes one level below prefix
+func (c *MinIOClient) ListPrefixes(ctx context.Context, bucket, prefix string) ([]string, []ObjectInfo, error) {
+	var prefixes []string
+	var objects []ObjectInfo
+
+	token := ""
+	for {
+		result, err := c.listPage(ctx, bucket, prefix, token, "/", maxListPageSize, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, common := range result.CommonPrefixes {
+			prefixes = append(prefixes, common.Prefix)
+		}
+		for _, obj := range result.Contents {
+			objects = append(objects, listedObject(obj))
+		}
+		if !result.IsTruncated {
+			return prefixes, objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// UpdateMetadata replaces an object's content type and user metadata in place
+func (c *MinIOClient) UpdateMetadata(ctx context.Context, bucket, key, etag string, opts PutOptions) error {
+	metadata := make(map[string]string, len(opts.Metadata)+1)
+	for k, v := range opts.Metadata {
+		metadata[k] = v
+	}
+	if opts.ContentType != "" {
+		metadata["Content-Type"] = opts.ContentType
+	}
+	// Replacing the metadata would otherwise drop the encoding of the data
+	if opts.ContentEncoding != "" {
+		metadata["Content-Encoding"] = opts.ContentEncoding
+	}
+	// A copy does not keep the ACL of the object it replaces
+	metadata = withGrants(metadata, opts.Grants)
+
+	src := minio.CopySrcOptions{
+		Bucket:    bucket,
+		Object:    key,
+		MatchETag: strings.Trim(etag, `"`),
+	}
+	dst := minio.CopyDestOptions{
+		Bucket:          bucket,
+		Object:          key,
+		ReplaceMetadata: true,
+		UserMetadata:    metadata,
+	}
+
+	_, err := c.client.CopyObject(ctx, dst, src)
+	return err
+}
+
+// RemoveObject deletes an object
+func (c *MinIOClient) RemoveObject(ctx context.Context, bucket, key string) error {
+	return c.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
+
+// BucketExists reports whether the bucket exists and is accessible
+func (c *MinIOClient) BucketExists(ctx context.Context, bucket string) (bool, error) {
+	return c.client.BucketExists(ctx, bucket)
+}
+
+// ListenBucketNotification subscribes to bucket notifications for the given event types
+func (c *MinIOClient) ListenBucketNotification(ctx context.Context, bucket, prefix string, events []string) (<-chan Event, <-chan error) {
+	eventCh := make(chan Event)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(eventCh)
+		defer close(errCh)
+
+		for info := range c.client.ListenBucketNotification(ctx, bucket, prefix, "", events) {
+			if info.Err != nil {
+				errCh <- info.Err
+				return
+			}
+
+			for _, record := range info.Records {
+				// Keys in notification records are URL-encoded
+				key, err := url.QueryUnescape(record.S3.Object.Key)
+				if err != nil {
+					errCh <- fmt.Errorf("invalid object key in event %q: %w", record.S3.Object.Key, err)
+					return
+				}
+
+				eventTime, _ := time.Parse(time.RFC3339, record.EventTime)
+
+				select {
+				case eventCh <- Event{
+					Name:         record.EventName,
+					Key:          key,
+					Size:         record.S3.Object.Size,
+					ETag:         record.S3.Object.ETag,
+					ContentType:  record.S3.Object.ContentType,
+					Metadata:     record.S3.Object.UserMetadata,
+					LastModified: eventTime,
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return eventCh, errCh
+}
+
 // NewMultipartUpload initiates a multipart upload
 func (c *MinIOClient) NewMultipartUpload(ctx context.Context, bucket, key string, opts PutOptions) (string, error) {
 	putOpts := minio.PutObjectOptions{
-		ContentType:  opts.ContentType,
-		UserMetadata: opts.Metadata,
+		ContentType:     opts.ContentType,
+		ContentEncoding: opts.ContentEncoding,
+		UserMetadata:    withGrants(opts.Metadata, opts.Grants),
+		UserTags:        opts.Tags,
 	}
 
 	// Use direct core API for multipart uploads
@@ -161,7 +554,7 @@ func (c *MinIOClient) UploadPart(ctx context.Context, bucket, key, uploadID stri
 }
 
 // CompleteMultipartUpload completes a multipart upload
-func (c *MinIOClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) error {
+func (c *MinIOClient) CompleteMultipartUpload(ctx context.Context, bucket, key, uploadID string, parts []CompletedPart) (string, error) {
 	minioParts := make([]minio.CompletePart, len(parts))
 	for i, part := range parts {
 		minioParts[i] = minio.CompletePart{
@@ -172,8 +565,11 @@ func (c *MinIOClient) CompleteMultipartUpload(ctx context.Context, bucket, key,
 
 	// Use direct core API for multipart uploads
 	core := &minio.Core{Client: c.client}
-	_, err := core.CompleteMultipartUpload(ctx, bucket, key, uploadID, minioParts, minio.PutObjectOptions{})
-	return err
+	info, err := core.CompleteMultipartUpload(ctx, bucket, key, uploadID, minioParts, minio.PutObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
 }
 
 // AbortMultipartUpload aborts a multipart upload
@@ -195,11 +591,13 @@ func (o *minioObject) Stat() (ObjectInfo, error) {
 	}
 
 	return ObjectInfo{
-		Key:          info.Key,
-		Size:         info.Size,
-		ETag:         info.ETag,
-		LastModified: info.LastModified,
-		ContentType:  info.ContentType, // Add ContentType field
-		Metadata:     info.UserMetadata,
+		Key:             info.Key,
+		Size:            info.Size,
+		ETag:            info.ETag,
+		LastModified:    info.LastModified,
+		ContentType:     info.ContentType, // Add ContentType field
+		ContentEncoding: info.Metadata.Get("Content-Encoding"),
+		Metadata:        info.UserMetadata,
+		Expiration:      info.Expiration,
 	}, nil
 }
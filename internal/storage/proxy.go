@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// proxyFunc returns the transport Proxy function for an explicitly configured
+// proxy. Credentials in the URL are sent as Proxy-Authorization. It returns nil
+// when no proxy is configured, leaving the HTTP(S)_PROXY environment in effect.
+func (cfg Config) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if cfg.Proxy == "" {
+		return nil, nil
+	}
+
+	proxyURL, err := url.Parse(cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	if (proxyURL.Scheme != "http" && proxyURL.Scheme != "https") || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL: expected http(s)://[user:password@]host:port")
+	}
+	return http.ProxyURL(proxyURL), nil
+}
@@ -0,0 +1,82 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// bandwidthChunk bounds a single read through the limiter, so that a large
+// read buffer does not hold back the other streams in one long wait
+const bandwidthChunk = 64 << 10
+
+// BandwidthLimiter caps the bytes read from the source per second across all
+// workers. Each read is charged after it completes: the bytes are booked
+// after those of earlier reads and the reader waits until they are paid off,
+// so the cap holds however many streams are open. Idle time does not build
+// up credit for a later burst.
+type BandwidthLimiter struct {
+	mu   sync.Mutex
+	rate float64   // Bytes per second
+	next time.Time // When the bytes booked so far are paid off
+}
+
+// NewBandwidthLimiter creates a limiter allowing bytesPerSecond bytes per second
+func NewBandwidthLimiter(bytesPerSecond int64) *BandwidthLimiter {
+	return &BandwidthLimiter{rate: float64(bytesPerSecond)}
+}
+
+// wait books n bytes and blocks until they are paid off or ctx is done
+func (l *BandwidthLimiter) wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(float64(n) / l.rate * float64(time.Second)))
+	until := l.next
+	l.mu.Unlock()
+
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reader wraps r so that reads from it count against the limit. A nil
+// limiter returns r unchanged.
+func (l *BandwidthLimiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &limitedReader{ctx: ctx, reader: r, limiter: l}
+}
+
+type limitedReader struct {
+	ctx     context.Context
+	reader  io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if len(b) > bandwidthChunk {
+		b = b[:bandwidthChunk]
+	}
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		if waitErr := r.limiter.wait(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"sync"
+
+	"minio2rustfs/internal/checkpoint"
+
+	"go.uber.org/zap"
+)
+
+// recordBatch buffers checkpoint records of a small-object batch so they are
+// written in one transaction once the whole batch finished
+type recordBatch struct {
+	mu      sync.Mutex
+	records []*checkpoint.TaskRecord
+}
+
+func (b *recordBatch) add(record *checkpoint.TaskRecord) {
+	b.mu.Lock()
+	b.records = append(b.records, record)
+	b.mu.Unlock()
+}
+
+// isSmall reports whether task qualifies for small-object batching
+func (p *Pool) isSmall(task Task) bool {
+	return p.config.SmallBatchSize > 1 && task.Size <= p.config.SmallBatchThreshold
+}
+
+// processSmallBatch gathers up to SmallBatchSize small tasks that are already
+// queued, starting with first, and transfers them concurrently so their
+// request latencies overlap. Their checkpoint records are saved together.
+// Larger tasks taken from the queue meanwhile are processed afterwards. It
+// reports whether tasks was closed while gathering.
+func (p *Pool) processSmallBatch(ctx context.Context, processor *TaskProcessor, first Task, tasks <-chan Task, checked bool) bool {
+	batch := []Task{first}
+	var deferred []Task
+	closed := false
+
+gather:
+	for len(batch) < p.config.SmallBatchSize {
+		select {
+		case task, ok := <-tasks:
+			if !ok {
+				closed = true
+				break gather
+			}
+			if p.isSmall(task) {
+				batch = append(batch, task)
+			} else {
+				deferred = append(deferred, task)
+				break gather
+			}
+		default:
+			break gather
+		}
+	}
+
+	bp := *processor
+	bp.records = &recordBatch{}
+
+	var wg sync.WaitGroup
+	for _, task := range batch {
+		wg.Add(1)
+		go func(task Task) {
+			defer wg.Done()
+			if checked {
+				bp.Transfer(ctx, task)
+			} else {
+				bp.Process(ctx, task)
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	if err := p.checkpoint.SaveTasks(bp.records.records); err != nil {
+		processor.logger.Error("Failed to save checkpoint records of small-object batch",
+			zap.Int("records", len(bp.records.records)),
+			zap.Error(err))
+	}
+
+	for _, task := range deferred {
+		if checked {
+			processor.Transfer(ctx, task)
+		} else {
+			processor.Process(ctx, task)
+		}
+	}
+
+	return closed
+}
@@ -0,0 +1,94 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// latencyClient delays every GET and PUT, standing in for the request round
+// trip of a real endpoint
+type latencyClient struct {
+	*storage.MemoryClient
+	latency time.Duration
+}
+
+func (c *latencyClient) GetObject(ctx context.Context, bucket, key string, opts storage.GetOptions) (storage.Object, error) {
+	time.Sleep(c.latency)
+	return c.MemoryClient.GetObject(ctx, bucket, key, opts)
+}
+
+func (c *latencyClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts storage.PutOptions) (string, error) {
+	time.Sleep(c.latency)
+	return c.MemoryClient.PutObject(ctx, bucket, key, reader, size, opts)
+}
+
+// BenchmarkSmallBatch migrates 20000 1KB objects with 16 workers, 2ms of
+// latency per GET and PUT and a SQLite checkpoint, for the --small-batch-size
+// values compared in the README. Run with:
+//
+//	go test ./internal/worker -run '^$' -bench SmallBatch -benchtime 1x
+func BenchmarkSmallBatch(b *testing.B) {
+	const (
+		objects = 20000
+		workers = 16
+		latency = 2 * time.Millisecond
+	)
+
+	src := storage.NewMemoryClient(testBucket)
+	data := testData(1024)
+	sources := make([]Task, objects)
+	for i := range sources {
+		key := fmt.Sprintf("small/%05d", i)
+		etag, err := src.PutObject(context.Background(), testBucket, key, bytes.NewReader(data), int64(len(data)), storage.PutOptions{})
+		if err != nil {
+			b.Fatalf("put source %s: %v", key, err)
+		}
+		sources[i] = Task{Bucket: testBucket, Key: key, Size: int64(len(data)), ETag: etag}
+	}
+
+	for _, size := range []int{0, 8, 32} {
+		b.Run(fmt.Sprintf("size=%d", size), func(b *testing.B) {
+			config := testConfig()
+			config.SmallBatchSize = size
+			config.SmallBatchThreshold = 4096
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				store, err := checkpoint.NewSQLiteStore(filepath.Join(b.TempDir(), "checkpoint.db"), checkpoint.SQLiteOptions{})
+				if err != nil {
+					b.Fatalf("open checkpoint: %v", err)
+				}
+				tasks := make(chan Task, objects)
+				for _, task := range sources {
+					tasks <- task
+				}
+				close(tasks)
+				srcClient := &latencyClient{MemoryClient: src, latency: latency}
+				dstClient := &latencyClient{MemoryClient: storage.NewMemoryClient(testBucket), latency: latency}
+				b.StartTimer()
+
+				var wg sync.WaitGroup
+				NewPool(workers, config, srcClient, dstClient, store, testMetrics, zap.NewNop()).Start(context.Background(), tasks, &wg)
+				wg.Wait()
+
+				b.StopTimer()
+				if err := store.Close(); err != nil {
+					b.Fatalf("close checkpoint: %v", err)
+				}
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(objects*b.N)/b.Elapsed().Seconds(), "objects/s")
+		})
+	}
+}
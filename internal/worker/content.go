@@ -0,0 +1,87 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// contentHash computes the SHA-256 of the source data as it is read for an
+// upload, so that the stored object can be compared against exactly the
+// bytes that were sent. The multipart path reads the source in order and
+// buffers parts before uploading them, so every byte is hashed once.
+type contentHash struct {
+	hash hash.Hash
+	size int64
+}
+
+// newContentHash returns nil when content verification is disabled
+func newContentHash(enabled bool) *contentHash {
+	if !enabled {
+		return nil
+	}
+	return &contentHash{hash: sha256.New()}
+}
+
+// Reader wraps r so that everything read from it is hashed. A nil hash
+// returns r unchanged.
+func (h *contentHash) Reader(r io.Reader) io.Reader {
+	if h == nil {
+		return r
+	}
+	return &hashingReader{reader: r, content: h}
+}
+
+type hashingReader struct {
+	reader  io.Reader
+	content *contentHash
+}
+
+func (r *hashingReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.content.hash.Write(b[:n])
+		r.content.size += int64(n)
+	}
+	return n, err
+}
+
+// verifyContent reads the uploaded object back and compares its SHA-256 with
+// the digest of the source data sent, catching corruption that size and ETag
+// checks miss, e.g. in multipart uploads whose ETag is not a content MD5. A
+// mismatch is retried with a fresh transfer.
+func (p *TaskProcessor) verifyContent(ctx context.Context, task Task, content *contentHash) error {
+	if content.size != task.Size {
+		return fmt.Errorf("upload of %s: %d bytes read from source, expected %d: %w", task.Key, content.size, task.Size, ErrChecksumMismatch)
+	}
+
+	obj, err := p.dstClient.GetObject(ctx, task.DestinationBucket(), task.DestinationKey(), storage.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to verify content of %s: %w", task.Key, err)
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		return fmt.Errorf("failed to verify content of %s: %w", task.Key, err)
+	}
+
+	src, dst := content.hash.Sum(nil), h.Sum(nil)
+	if !bytes.Equal(src, dst) {
+		p.logger.Warn("Destination content differs from source after upload",
+			zap.String("key", task.Key),
+			zap.String("dst_key", task.DestinationKey()),
+			zap.String("src_sha256", fmt.Sprintf("%x", src)),
+			zap.String("dst_sha256", fmt.Sprintf("%x", dst)),
+		)
+		return fmt.Errorf("upload of %s: source sha256 %x, destination sha256 %x: %w", task.Key, src, dst, ErrChecksumMismatch)
+	}
+	return nil
+}
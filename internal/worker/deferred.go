@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// deferredRetries transfers tasks again after a cooldown once their attempts
+// are used up, for failures that resolve slowly (e.g. a destination bucket
+// still being provisioned). Each scheduled retry runs in its own goroutine
+// counted in the WaitGroup of the current Start, so a pass does not finish
+// while retries are pending.
+type deferredRetries struct {
+	pool   *Pool
+	logger *zap.Logger
+
+	mu sync.Mutex
+	wg *sync.WaitGroup
+}
+
+// setWaitGroup sets the WaitGroup scheduled retries are counted in
+func (d *deferredRetries) setWaitGroup(wg *sync.WaitGroup) {
+	d.mu.Lock()
+	d.wg = wg
+	d.mu.Unlock()
+}
+
+// schedule transfers task again after the cooldown. The caller must itself be
+// counted in the WaitGroup, so that adding to it cannot race with Wait.
+func (d *deferredRetries) schedule(ctx context.Context, task Task, lastErr error) {
+	d.mu.Lock()
+	wg := d.wg
+	d.mu.Unlock()
+
+	task.Deferrals++
+	cooldown := d.pool.config.RetryCooldown
+	d.logger.Warn("Deferring failed task for retry after cooldown",
+		zap.String("key", task.Key),
+		zap.Int("deferral", task.Deferrals),
+		zap.Duration("cooldown", cooldown),
+		zap.Error(lastErr),
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		processor := d.pool.newProcessor(-1, d.logger)
+		select {
+		case <-time.After(cooldown):
+		case <-ctx.Done():
+			// Record the outcome so far; a resumed run retries it
+			processor.markFailed(task, lastErr)
+			processor.metrics.IncFailed(storage.ErrorCategory(lastErr), task.Size)
+			return
+		}
+
+		processor.Transfer(ctx, task)
+	}()
+}
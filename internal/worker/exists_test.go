@@ -0,0 +1,91 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// headErrorClient fails every HeadObject with err
+type headErrorClient struct {
+	*storage.MemoryClient
+	err error
+}
+
+func (c headErrorClient) HeadObject(ctx context.Context, bucket, key string) (storage.ObjectInfo, error) {
+	return storage.ObjectInfo{}, c.err
+}
+
+func TestObjectExistsAndMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "404", err: minio.ErrorResponse{StatusCode: http.StatusNotFound, Code: "NoSuchKey"}},
+		{name: "not found", err: storage.ErrNotFound},
+		{name: "403", err: minio.ErrorResponse{StatusCode: http.StatusForbidden, Code: "AccessDenied"}, wantErr: true},
+		{name: "500", err: minio.ErrorResponse{StatusCode: http.StatusInternalServerError, Code: "InternalError"}, wantErr: true},
+		{name: "network", err: errors.New("connection reset by peer"), wantErr: true},
+	}
+
+	config := testConfig()
+	config.SkipExisting = true
+	config.CompareSize = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := headErrorClient{MemoryClient: storage.NewMemoryClient(testBucket), err: tt.err}
+			p := newTestProcessor(t, config, storage.NewMemoryClient(testBucket), dst, checkpoint.NewNoopStore())
+
+			_, ok, err := p.objectExistsAndMatches(context.Background(), Task{Bucket: testBucket, Key: "object", Size: 1})
+			if ok {
+				t.Fatalf("object reported as existing")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("error %v does not wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSkipExistingHeadErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus checkpoint.TaskStatus
+	}{
+		{name: "404 uploads", err: minio.ErrorResponse{StatusCode: http.StatusNotFound, Code: "NoSuchKey"}, wantStatus: checkpoint.StatusCompleted},
+		{name: "403 fails", err: minio.ErrorResponse{StatusCode: http.StatusForbidden, Code: "AccessDenied"}, wantStatus: checkpoint.StatusFailed},
+		{name: "500 fails", err: minio.ErrorResponse{StatusCode: http.StatusInternalServerError, Code: "InternalError"}, wantStatus: checkpoint.StatusFailed},
+	}
+
+	config := testConfig()
+	config.SkipExisting = true
+	config.CompareSize = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := storage.NewMemoryClient(testBucket)
+			dst := storage.NewMemoryClient(testBucket)
+			store := newTestStore(t)
+			task := putSource(t, src, "object", testData(1024), config.PartSize, storage.PutOptions{})
+
+			p := newTestProcessor(t, config, src, headErrorClient{MemoryClient: dst, err: tt.err}, store)
+			p.Process(context.Background(), task)
+
+			assertStatus(t, store, task.Key, tt.wantStatus)
+			_, err := dst.Data(testBucket, task.Key)
+			if uploaded := err == nil; uploaded != (tt.wantStatus == checkpoint.StatusCompleted) {
+				t.Fatalf("uploaded %v, want %v", uploaded, tt.wantStatus == checkpoint.StatusCompleted)
+			}
+		})
+	}
+}
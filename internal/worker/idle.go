@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// idleWatchdog cancels a transfer when no bytes have moved for the configured
+// timeout. Readers on both sides of a transfer report progress to it: the
+// source stream as it is read and part buffers as the destination consumes
+// them, so a stall in either direction trips the watchdog.
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	stalled atomic.Bool
+}
+
+// newIdleWatchdog returns a context that is cancelled once the transfer has
+// been idle for timeout
+func newIdleWatchdog(ctx context.Context, timeout time.Duration) (context.Context, *idleWatchdog) {
+	ctx, cancel := context.WithCancel(ctx)
+	w := &idleWatchdog{
+		timeout: timeout,
+		cancel:  cancel,
+	}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.stalled.Store(true)
+		cancel()
+	})
+	return ctx, w
+}
+
+// Reader wraps r so that every successful read resets the idle timer. A nil
+// watchdog returns r unchanged.
+func (w *idleWatchdog) Reader(r io.Reader) io.Reader {
+	if w == nil {
+		return r
+	}
+	return &idleReader{reader: r, watchdog: w}
+}
+
+// Stop releases the watchdog
+func (w *idleWatchdog) Stop() {
+	if w == nil {
+		return
+	}
+	w.timer.Stop()
+	w.cancel()
+}
+
+// Err annotates err when the transfer was aborted by the watchdog
+func (w *idleWatchdog) Err(err error) error {
+	if w == nil || err == nil || !w.stalled.Load() {
+		return err
+	}
+	return fmt.Errorf("idle timeout: no data transferred for %s: %w", w.timeout, err)
+}
+
+type idleReader struct {
+	reader   io.Reader
+	watchdog *idleWatchdog
+}
+
+func (r *idleReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.watchdog.timer.Reset(r.watchdog.timeout)
+	}
+	return n, err
+}
@@ -0,0 +1,280 @@
+package worker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// PackManifest describes the contents of a packed tar archive. It is uploaded
+// next to the archive as "<archive>.manifest.json" so objects can be unpacked later.
+type PackManifest struct {
+	Archive   string              `json:"archive"`
+	CreatedAt time.Time           `json:"created_at"`
+	Objects   []PackManifestEntry `json:"objects"`
+}
+
+// PackManifestEntry describes a single object inside a packed archive.
+// Offset is the byte offset of the object's data within the tar file.
+type PackManifestEntry struct {
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	ETag         string            `json:"etag"`
+	ContentType  string            `json:"content_type,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+	Offset       int64             `json:"offset"`
+}
+
+// Packer groups small objects into tar archives to cut per-object request overhead.
+// It is shared by all workers; whichever worker fills a batch uploads it.
+type Packer struct {
+	mu           sync.Mutex
+	processor    *TaskProcessor
+	pending      []Task
+	pendingBytes int64
+	runID        string
+	seq          int
+}
+
+// NewPacker creates a packer that uses processor for clients, checkpointing and metrics
+func NewPacker(processor *TaskProcessor) *Packer {
+	return &Packer{
+		processor: processor,
+		runID:     time.Now().UTC().Format("20060102T150405Z"),
+	}
+}
+
+// Add queues a task for packing and uploads the batch once it reaches PackMaxSize
+func (pk *Packer) Add(ctx context.Context, task Task) {
+	pk.mu.Lock()
+	pk.pending = append(pk.pending, task)
+	pk.pendingBytes += task.Size
+	if pk.pendingBytes < pk.processor.config.PackMaxSize {
+		pk.mu.Unlock()
+		return
+	}
+	batch, archiveKey := pk.takeBatch()
+	pk.mu.Unlock()
+
+	pk.upload(ctx, batch, archiveKey)
+}
+
+// Flush uploads any partially filled batch
+func (pk *Packer) Flush(ctx context.Context) {
+	pk.mu.Lock()
+	if len(pk.pending) == 0 {
+		pk.mu.Unlock()
+		return
+	}
+	batch, archiveKey := pk.takeBatch()
+	pk.mu.Unlock()
+
+	pk.upload(ctx, batch, archiveKey)
+}
+
+// takeBatch detaches the pending batch and names its archive (must be called with lock held)
+func (pk *Packer) takeBatch() ([]Task, string) {
+	batch := pk.pending
+	pk.pending = nil
+	pk.pendingBytes = 0
+	pk.seq++
+
+	archiveKey := path.Join(pk.processor.config.PackPrefix, fmt.Sprintf("%s-%06d.tar", pk.runID, pk.seq))
+	return batch, archiveKey
+}
+
+func (pk *Packer) upload(ctx context.Context, batch []Task, archiveKey string) {
+	p := pk.processor
+	startTime := time.Now()
+	bucket := batch[0].DestinationBucket()
+
+	manifest, archiveSize, err := buildManifest(batch, archiveKey)
+	if err == nil {
+		err = pk.uploadWithRetry(ctx, bucket, archiveKey, batch, manifest, archiveSize)
+	}
+
+	if err != nil {
+		p.logger.Error("Failed to upload packed archive",
+			zap.String("archive", archiveKey),
+			zap.Int("objects", len(batch)),
+			zap.Error(err),
+		)
+		for _, task := range batch {
+			p.markFailed(task, fmt.Errorf("packed archive %s: %w", archiveKey, err))
+			p.metrics.IncFailed(storage.ErrorCategory(err), task.Size)
+		}
+		return
+	}
+
+	for _, task := range batch {
+		p.markCompleted(task, "")
+		p.metrics.IncSuccessWithBytes(task.Size)
+		p.metrics.AddBytes(task.Size)
+		p.metrics.AddContentTypeBytes(task.ContentType, task.Size)
+	}
+	p.metrics.ObserveDuration(time.Since(startTime))
+	p.logger.Info("Packed archive uploaded",
+		zap.String("archive", archiveKey),
+		zap.Int("objects", len(batch)),
+		zap.Int64("size", archiveSize),
+		zap.Duration("duration", time.Since(startTime)),
+	)
+}
+
+func (pk *Packer) uploadWithRetry(ctx context.Context, bucket, archiveKey string, batch []Task, manifest *PackManifest, archiveSize int64) error {
+	p := pk.processor
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= p.config.Retries; attempt++ {
+		lastErr = pk.uploadArchive(ctx, bucket, archiveKey, batch, archiveSize)
+		if lastErr == nil {
+			_, lastErr = p.dstClient.PutObject(ctx, bucket, archiveKey+".manifest.json",
+				bytes.NewReader(manifestData), int64(len(manifestData)),
+				storage.PutOptions{ContentType: "application/json"})
+		}
+		if lastErr == nil || !p.isRetriableError(lastErr) {
+			return lastErr
+		}
+
+		p.logger.Warn("Packed archive upload attempt failed",
+			zap.String("archive", archiveKey),
+			zap.Int("attempt", attempt),
+			zap.Error(lastErr),
+		)
+		if attempt < p.config.Retries {
+			time.Sleep(p.calculateBackoff(attempt))
+		}
+	}
+
+	return lastErr
+}
+
+// uploadArchive streams the tar archive to the destination while reading each source object
+func (pk *Packer) uploadArchive(ctx context.Context, bucket, archiveKey string, batch []Task, archiveSize int64) error {
+	p := pk.processor
+	pr, pw := io.Pipe()
+
+	go func() {
+		pw.CloseWithError(pk.writeArchive(ctx, pw, batch))
+	}()
+
+	_, err := p.dstClient.PutObject(ctx, bucket, archiveKey, pr, archiveSize, storage.PutOptions{
+		ContentType:      "application/x-tar",
+		DisableMultipart: p.config.NoMultipart,
+	})
+	pr.CloseWithError(err)
+	return err
+}
+
+func (pk *Packer) writeArchive(ctx context.Context, w io.Writer, batch []Task) error {
+	p := pk.processor
+	tw := tar.NewWriter(w)
+
+	for _, task := range batch {
+		if err := p.acquireRead(ctx); err != nil {
+			return err
+		}
+		obj, err := p.srcClient.GetObject(ctx, task.Bucket, task.Key, storage.GetOptions{})
+		if err != nil {
+			p.releaseRead()
+			return fmt.Errorf("failed to get source object %s: %w", task.Key, err)
+		}
+
+		err = tw.WriteHeader(tarHeader(task))
+		if err == nil {
+			var n int64
+			n, err = io.CopyN(tw, p.bandwidth.Reader(ctx, obj), task.Size)
+			if err != nil && n < task.Size {
+				err = fmt.Errorf("source object %s shorter than listed size (%d < %d): %w", task.Key, n, task.Size, err)
+			}
+		}
+		obj.Close()
+		p.releaseRead()
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+// buildManifest computes the exact archive size and per-object data offsets by
+// writing the tar structure with zeroed contents to a counting writer.
+func buildManifest(batch []Task, archiveKey string) (*PackManifest, int64, error) {
+	counter := &countingWriter{}
+	tw := tar.NewWriter(counter)
+
+	manifest := &PackManifest{
+		Archive:   archiveKey,
+		CreatedAt: time.Now().UTC(),
+		Objects:   make([]PackManifestEntry, 0, len(batch)),
+	}
+
+	for _, task := range batch {
+		if err := tw.WriteHeader(tarHeader(task)); err != nil {
+			return nil, 0, fmt.Errorf("failed to build tar header for %s: %w", task.Key, err)
+		}
+		// The header is flushed to the counter once WriteHeader returns
+		manifest.Objects = append(manifest.Objects, PackManifestEntry{
+			Key:          task.DestinationKey(),
+			Size:         task.Size,
+			ETag:         task.ETag,
+			ContentType:  task.ContentType,
+			Metadata:     task.Metadata,
+			LastModified: task.LastModified,
+			Offset:       counter.n,
+		})
+		if _, err := io.CopyN(tw, zeroReader{}, task.Size); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	return manifest, counter.n, nil
+}
+
+func tarHeader(task Task) *tar.Header {
+	return &tar.Header{
+		Name:    task.DestinationKey(),
+		Size:    task.Size,
+		Mode:    0644,
+		ModTime: task.LastModified,
+	}
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
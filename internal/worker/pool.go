@@ -20,6 +20,14 @@ type Pool struct {
 	checkpoint checkpoint.Store
 	metrics    *metrics.Collector
 	logger     *zap.Logger
+	packer     *Packer
+	throttle   *Throttle
+	reads      *ReadLimiter      // nil when source reads are unlimited
+	rate       *RateLimiter      // nil when object starts are not rate limited
+	bandwidth  *BandwidthLimiter // nil when source reads are not bandwidth limited
+	deferred   *deferredRetries  // nil when deferred retries are disabled
+	tags       *tagCopier        // nil when object tags are not copied
+	buffers    *sync.Pool        // Part buffers, shared by all workers when PoolBuffers is set
 }
 
 // NewPool creates a new worker pool
@@ -32,7 +40,7 @@ func NewPool(
 	metricsCollector *metrics.Collector,
 	logger *zap.Logger,
 ) *Pool {
-	return &Pool{
+	p := &Pool{
 		size:       size,
 		config:     config,
 		srcClient:  srcClient,
@@ -41,30 +49,125 @@ func NewPool(
 		metrics:    metricsCollector,
 		logger:     logger,
 	}
+
+	if config.PoolBuffers {
+		p.buffers = &sync.Pool{New: func() any {
+			buf := make([]byte, config.PartSize)
+			return &buf
+		}}
+	}
+
+	if config.AutoThrottle {
+		p.throttle = NewThrottle(config.ThrottleMaxDelay, metricsCollector, logger.With(zap.String("component", "throttle")))
+	}
+
+	if config.DeferredRetries > 0 {
+		p.deferred = &deferredRetries{pool: p, logger: logger.With(zap.String("component", "deferred-retry"))}
+	}
+
+	if config.MaxSourceReads > 0 {
+		p.reads = NewReadLimiter(config.MaxSourceReads, metricsCollector)
+	}
+
+	if config.MaxObjectsPerSecond > 0 {
+		p.rate = NewRateLimiter(config.MaxObjectsPerSecond)
+	}
+
+	if config.MaxBandwidth > 0 {
+		p.bandwidth = NewBandwidthLimiter(config.MaxBandwidth)
+	}
+
+	p.tags = newTagCopier(config.CopyTags, logger.With(zap.String("component", "tags")))
+
+	if config.PackSmall {
+		p.packer = NewPacker(p.newProcessor(-1, logger.With(zap.String("component", "packer"))))
+	}
+
+	return p
 }
 
 // Start starts the worker pool
 func (p *Pool) Start(ctx context.Context, tasks <-chan Task, wg *sync.WaitGroup) {
+	if p.deferred != nil {
+		p.deferred.setWaitGroup(wg)
+	}
+	if p.config.Priorities != nil {
+		tasks = p.startDispatcher(ctx, tasks, wg)
+	}
+
+	// With a separate head concurrency, existence checks run in their own
+	// goroutines and only tasks that still need transferring reach the workers
+	checked := p.config.HeadConcurrency > 0
+	if checked {
+		tasks = p.startCheckers(ctx, tasks, wg)
+	}
+
 	for i := 0; i < p.size; i++ {
 		wg.Add(1)
-		go p.worker(ctx, i, tasks, wg)
+		go p.worker(ctx, i, tasks, checked, wg)
 	}
 }
 
-func (p *Pool) worker(ctx context.Context, id int, tasks <-chan Task, wg *sync.WaitGroup) {
+// startCheckers starts HeadConcurrency checkers that skip already migrated
+// tasks and forward the rest on the returned channel, which is closed once
+// tasks is drained.
+func (p *Pool) startCheckers(ctx context.Context, tasks <-chan Task, wg *sync.WaitGroup) <-chan Task {
+	pending := make(chan Task, p.config.QueueDepth)
+
+	var checkers sync.WaitGroup
+	for i := 0; i < p.config.HeadConcurrency; i++ {
+		checkers.Add(1)
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			defer checkers.Done()
+			p.checker(ctx, id, tasks, pending)
+		}(i)
+	}
+
+	go func() {
+		checkers.Wait()
+		close(pending)
+	}()
+
+	return pending
+}
+
+func (p *Pool) checker(ctx context.Context, id int, tasks <-chan Task, pending chan<- Task) {
+	processor := p.newProcessor(-1, p.logger.With(zap.Int("checker_id", id)))
+
+	for {
+		select {
+		case task, ok := <-tasks:
+			if !ok {
+				return
+			}
+
+			if !processor.NeedsTransfer(ctx, &task) {
+				continue
+			}
+
+			select {
+			case pending <- task:
+			case <-ctx.Done():
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// worker processes tasks; with checked set the tasks already passed the
+// existence checks and are transferred directly
+func (p *Pool) worker(ctx context.Context, id int, tasks <-chan Task, checked bool, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	logger := p.logger.With(zap.Int("worker_id", id))
 	logger.Info("Worker started")
 
-	processor := &TaskProcessor{
-		config:     p.config,
-		srcClient:  p.srcClient,
-		dstClient:  p.dstClient,
-		checkpoint: p.checkpoint,
-		metrics:    p.metrics,
-		logger:     logger,
-	}
+	processor := p.newProcessor(id, logger)
 
 	for {
 		select {
@@ -74,7 +177,16 @@ func (p *Pool) worker(ctx context.Context, id int, tasks <-chan Task, wg *sync.W
 				return
 			}
 
-			processor.Process(ctx, task)
+			if p.isSmall(task) {
+				if closed := p.processSmallBatch(ctx, processor, task, tasks, checked); closed {
+					logger.Info("Worker finished - no more tasks")
+					return
+				}
+			} else if checked {
+				processor.Transfer(ctx, task)
+			} else {
+				processor.Process(ctx, task)
+			}
 
 		case <-ctx.Done():
 			logger.Info("Worker stopped - context cancelled")
@@ -82,3 +194,32 @@ func (p *Pool) worker(ctx context.Context, id int, tasks <-chan Task, wg *sync.W
 		}
 	}
 }
+
+// Flush uploads any objects still buffered for packing. Call it after all workers finish.
+func (p *Pool) Flush(ctx context.Context) {
+	if p.packer != nil {
+		p.packer.Flush(ctx)
+	}
+}
+
+// newProcessor creates a processor for worker id; the packer uses a negative
+// id and is not shown in the per-worker progress
+func (p *Pool) newProcessor(id int, logger *zap.Logger) *TaskProcessor {
+	return &TaskProcessor{
+		id:         id,
+		config:     p.config,
+		srcClient:  p.srcClient,
+		dstClient:  p.dstClient,
+		checkpoint: p.checkpoint,
+		metrics:    p.metrics,
+		logger:     logger,
+		packer:     p.packer,
+		throttle:   p.throttle,
+		reads:      p.reads,
+		rate:       p.rate,
+		bandwidth:  p.bandwidth,
+		deferred:   p.deferred,
+		tags:       p.tags,
+		buffers:    p.buffers,
+	}
+}
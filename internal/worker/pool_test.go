@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// cancellingClient cancels the run after a number of uploads, like a SIGTERM
+// arriving mid-run
+type cancellingClient struct {
+	*storage.MemoryClient
+	after  int32
+	puts   atomic.Int32
+	cancel context.CancelFunc
+}
+
+func (c *cancellingClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts storage.PutOptions) (string, error) {
+	etag, err := c.MemoryClient.PutObject(ctx, bucket, key, reader, size, opts)
+	if c.puts.Add(1) == c.after {
+		c.cancel()
+	}
+	return etag, err
+}
+
+// TestPoolCancelPersistsCompletions cancels a run while small-object batches
+// buffer their checkpoint records and checks that, once the checkpoint is
+// closed, every object that reached the destination is recorded as completed
+func TestPoolCancelPersistsCompletions(t *testing.T) {
+	const objects = 40
+	config := testConfig()
+	config.SmallBatchSize = 4
+	config.SmallBatchThreshold = 4096
+
+	src := storage.NewMemoryClient(testBucket)
+	tasks := make(chan Task, objects)
+	for i := 0; i < objects; i++ {
+		tasks <- putSource(t, src, fmt.Sprintf("small/%03d", i), testData(1024), config.PartSize, storage.PutOptions{})
+	}
+	close(tasks)
+
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+	store, err := checkpoint.NewSQLiteStore(path, checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("open checkpoint: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dst := &cancellingClient{MemoryClient: storage.NewMemoryClient(testBucket), after: 10, cancel: cancel}
+
+	var wg sync.WaitGroup
+	NewPool(2, config, src, dst, store, testMetrics, zap.NewNop()).Start(ctx, tasks, &wg)
+	wg.Wait()
+	if err := store.Close(); err != nil {
+		t.Fatalf("close checkpoint: %v", err)
+	}
+
+	store, err = checkpoint.NewSQLiteStore(path, checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("reopen checkpoint: %v", err)
+	}
+	defer store.Close()
+
+	uploaded := 0
+	for i := 0; i < objects; i++ {
+		key := fmt.Sprintf("small/%03d", i)
+		record, err := store.GetTask(testBucket, key)
+		if err != nil {
+			t.Fatalf("get %s: %v", key, err)
+		}
+		completed := record != nil && record.Status == checkpoint.StatusCompleted
+		_, err = dst.Data(testBucket, key)
+		if exists := err == nil; exists != completed {
+			t.Fatalf("%s: on destination %v, completed in checkpoint %v", key, exists, completed)
+		}
+		if completed {
+			uploaded++
+		}
+	}
+	if uploaded < int(dst.after) || uploaded == objects {
+		t.Fatalf("%d of %d objects completed, want the run interrupted after at least %d", uploaded, objects, dst.after)
+	}
+}
@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+)
+
+// prioritizedTask is a queued task with its manifest priority. Tasks not in
+// the manifest sort after all listed ones; ties keep the listing order.
+type prioritizedTask struct {
+	task     Task
+	priority int
+	listed   bool
+	seq      int64
+}
+
+// taskHeap is a max-heap of prioritized tasks
+type taskHeap []prioritizedTask
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.listed != b.listed {
+		return a.listed
+	}
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+func (h taskHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *taskHeap) Push(x any) { *h = append(*h, x.(prioritizedTask)) }
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// startDispatcher drains tasks into a priority queue as fast as they arrive
+// and hands out the highest-priority queued task whenever a worker is ready.
+// Draining eagerly lets high-priority objects listed late overtake the rest,
+// at the cost of holding the queued tasks in memory. The returned channel is
+// closed once tasks is closed and the queue is empty.
+func (p *Pool) startDispatcher(ctx context.Context, tasks <-chan Task, wg *sync.WaitGroup) <-chan Task {
+	out := make(chan Task)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(out)
+
+		queue := &taskHeap{}
+		var seq int64
+		push := func(task Task) {
+			priority, listed := p.config.Priorities[task.Key]
+			heap.Push(queue, prioritizedTask{task: task, priority: priority, listed: listed, seq: seq})
+			seq++
+		}
+
+		for tasks != nil || queue.Len() > 0 {
+			// Take in everything already waiting before handing out the top task
+			select {
+			case task, ok := <-tasks:
+				if ok {
+					push(task)
+				} else {
+					tasks = nil
+				}
+				continue
+			default:
+			}
+
+			// Sending is only enabled while something is queued
+			var send chan<- Task
+			var next Task
+			if queue.Len() > 0 {
+				send = out
+				next = (*queue)[0].task
+			}
+
+			select {
+			case task, ok := <-tasks:
+				if !ok {
+					tasks = nil
+					continue
+				}
+				push(task)
+			case send <- next:
+				heap.Pop(queue)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
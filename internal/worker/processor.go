@@ -3,10 +3,15 @@ package worker
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"math"
+	"os"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"minio2rustfs/internal/checkpoint"
@@ -18,44 +23,211 @@ import (
 
 // TaskProcessor handles individual task processing
 type TaskProcessor struct {
+	id         int // Worker ID, used for per-worker progress
 	config     Config
 	srcClient  storage.Client
 	dstClient  storage.Client
 	checkpoint checkpoint.Store
 	metrics    *metrics.Collector
 	logger     *zap.Logger
+	packer     *Packer
+	throttle   *Throttle
+	reads      *ReadLimiter      // Bounds concurrent source reads; nil is unlimited
+	rate       *RateLimiter      // Spaces out object transfers; nil is unlimited
+	bandwidth  *BandwidthLimiter // Caps source read bytes per second; nil is unlimited
+	deferred   *deferredRetries
+	tags       *tagCopier   // Copies source object tags; nil leaves them out
+	records    *recordBatch // Buffers checkpoint records while processing a small-object batch
+	buffers    *sync.Pool   // Reusable part buffers; nil allocates a buffer per part
 }
 
 // Process processes a single migration task
 func (p *TaskProcessor) Process(ctx context.Context, task Task) {
-	startTime := time.Now()
+	if p.NeedsTransfer(ctx, &task) {
+		p.Transfer(ctx, task)
+	}
+}
+
+// NeedsTransfer runs the checkpoint and destination existence checks for task,
+// recording it as skipped when it is already migrated. It reports whether the
+// task still has to be transferred. With Conditional set, the ETag of an
+// existing destination object that did not match is stored in task.DstETag.
+func (p *TaskProcessor) NeedsTransfer(ctx context.Context, task *Task) (needed bool) {
+	defer p.recoverPanic(*task)
+
+	// Check if task is already completed. A fresh run has nothing to find in the
+	// checkpoint, so the lookup is skipped and only the destination check applies.
+	if p.config.Resume {
+		if record, err := p.checkpoint.GetTask(task.Bucket, task.CheckpointKey()); err == nil && record != nil {
+			// In watch mode later passes re-list changed objects, and with
+			// RecheckSource the source may have changed since it was migrated, so
+			// a completed record only counts if it still matches the listed object.
+			recheck := p.config.Watch || p.config.RecheckSource
+			changed := recheck && (record.Size != task.Size || record.ETag != task.ETag)
+			if changed && record.Status == checkpoint.StatusCompleted {
+				p.logger.Info("Source object changed since it was migrated, re-migrating",
+					zap.String("key", task.Key),
+					zap.Int64("checkpoint_size", record.Size),
+					zap.Int64("source_size", task.Size),
+				)
+			}
+			// Packed objects do not exist under their own key, so they cannot
+			// be verified on the destination
+			verify := p.config.VerifyCompleted && !p.packs(*task)
+			if record.Status == checkpoint.StatusCompleted && p.config.SkipExisting && !changed &&
+				(!verify || p.completedOnDestination(ctx, *task, record)) {
+				p.logger.Debug("Skipping completed task", zap.String("key", task.Key))
+				p.metrics.IncSkippedCompleted(task.Size)
+				return false
+			}
+			if p.quarantined(*task, record) {
+				return false
+			}
+		}
+	}
 
-	// Check if task is already completed
-	if record, err := p.checkpoint.GetTask(task.Bucket, task.Key); err == nil && record != nil {
-		if record.Status == checkpoint.StatusCompleted && p.config.SkipExisting {
-			p.logger.Debug("Skipping completed task", zap.String("key", task.Key))
+	if p.expiresSoon(ctx, *task) {
+		return false
+	}
+
+	// Small objects are packed into archives, so they never exist under their own key
+	if p.packs(*task) {
+		return true
+	}
+
+	// Check if object exists in destination with same size/etag (or is not older, with copy-if-newer)
+	if p.config.SkipExisting || p.config.CopyIfNewer {
+		dstInfo, ok, err := p.objectExistsAndMatches(ctx, *task)
+		if err != nil {
+			// A denied check means the upload would be denied as well, so the
+			// task fails right away with the cause instead of a confusing
+			// upload error. Other failures must not be taken for a missing
+			// object either.
+			p.markFailed(*task, err)
+			p.metrics.IncFailed(storage.ErrorCategory(err), task.Size)
+			p.logger.Error("Task failed, destination existence check failed",
+				zap.String("key", task.Key),
+				zap.String("dst_key", task.DestinationKey()),
+				zap.Error(err),
+			)
+			return false
+		}
+		if ok {
+			if p.config.SyncMetadata && task.Range == nil {
+				return !p.syncMetadata(ctx, *task, dstInfo)
+			}
+			p.logger.Debug("Skipping existing object", zap.String("key", task.Key))
+			p.markCompleted(*task, dstInfo.ETag)
 			p.metrics.IncSkippedWithBytes(task.Size) // Use new method with bytes
+			return false
+		}
+		// The source may still hold the same data (e.g. a newer but identical
+		// copy), which a conditional read against this ETag detects
+		if p.config.Conditional {
+			task.DstETag = dstInfo.ETag
+		}
+	}
+
+	return true
+}
+
+// Transfer migrates task with retries. It does not check whether the task was
+// already migrated; call NeedsTransfer first.
+func (p *TaskProcessor) Transfer(ctx context.Context, task Task) {
+	defer p.recoverPanic(task)
+	startTime := time.Now()
+
+	if contentType, ok := p.config.ContentTypes[strings.ToLower(path.Ext(task.Key))]; ok {
+		task.ContentType = contentType
+	}
+
+	// Skipped objects never get here, so only transfers count against the rate
+	if p.rate != nil {
+		if err := p.rate.Wait(ctx); err != nil {
+			p.markFailed(task, err)
+			p.metrics.IncFailed(storage.ErrorCategory(err), task.Size)
 			return
 		}
 	}
 
-	// Check if object exists in destination with same size/etag
-	if p.config.SkipExisting && p.objectExistsAndMatches(ctx, task) {
-		p.logger.Debug("Skipping existing object", zap.String("key", task.Key))
-		p.markCompleted(task)
-		p.metrics.IncSkippedWithBytes(task.Size) // Use new method with bytes
+	if p.packs(task) {
+		p.packer.Add(ctx, task)
 		return
 	}
 
+	if p.tracksProgress() {
+		defer p.metrics.FinishWorkerTask(p.id)
+	}
+
+	// The object budget covers every attempt and the backoff between them;
+	// runCtx tells a cancelled run apart from a spent budget
+	runCtx := ctx
+	if p.config.ObjectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.ObjectTimeout)
+		defer cancel()
+	}
+
 	// Process with retry logic
 	var lastErr error
+	attempts := 0
+	mismatches := 0
 	for attempt := 1; attempt <= p.config.Retries; attempt++ {
-		err := p.processTask(ctx, task)
+		if err := ctx.Err(); err != nil {
+			if lastErr == nil {
+				lastErr = err
+			}
+			break
+		}
+		attempts = attempt
+		if p.throttle != nil {
+			if err := p.throttle.Wait(ctx); err != nil {
+				lastErr = err
+				break
+			}
+		}
+
+		dstETag, err := p.processTask(ctx, task)
+		if p.throttle != nil {
+			// A conditional skip is a request the server answered normally
+			if errors.Is(err, errIdentical) {
+				p.throttle.Record(nil)
+			} else {
+				p.throttle.Record(err)
+			}
+		}
+		// The destination rejected the object's tags and tag copying is now
+		// off; the upload is repeated without them and keeps its attempt
+		if errors.Is(err, errTagsUnsupported) {
+			attempt--
+			continue
+		}
+
 		if err == nil {
+			// A checksum mismatch usually means the data was corrupted in
+			// transit, so the object is transferred again from a fresh GET. These
+			// re-transfers have their own budget and do not use up attempts.
+			if mismatch := p.checkUploadETag(task, dstETag); mismatch != nil && p.config.ChecksumRetries > 0 {
+				if mismatches < p.config.ChecksumRetries {
+					mismatches++
+					attempt--
+					p.logger.Warn("Re-transferring object after checksum mismatch",
+						zap.String("key", task.Key),
+						zap.Int("checksum_retry", mismatches),
+					)
+					continue
+				}
+				lastErr = mismatch
+				break
+			}
+
+			p.logIfSlow(task, startTime, attempt)
+
 			// Mark as completed and update metrics
-			p.markCompleted(task)
+			p.markCompleted(task, dstETag)
 			p.metrics.IncSuccessWithBytes(task.Size) // Use new method with bytes
 			p.metrics.AddBytes(task.Size)
+			p.metrics.AddContentTypeBytes(task.ContentType, task.Size)
 			p.metrics.ObserveDuration(time.Since(startTime))
 			p.logger.Info("Task completed successfully",
 				zap.String("key", task.Key),
@@ -65,6 +237,32 @@ func (p *TaskProcessor) Process(ctx context.Context, task Task) {
 			return
 		}
 
+		// A conditional request showed that the destination already holds the
+		// same data
+		if errors.Is(err, errIdentical) {
+			dstETag := task.DstETag
+			if dstETag == "" {
+				dstETag = task.ETag
+			}
+			p.logger.Debug("Skipping object identical on the destination", zap.String("key", task.Key))
+			p.markCompleted(task, dstETag)
+			p.metrics.IncSkippedWithBytes(task.Size)
+			return
+		}
+
+		// Retention on a locked destination object outlasts any retry, so the
+		// object is skipped instead of counted as a failure
+		if storage.IsObjectLocked(err) {
+			p.markLocked(task, err)
+			p.metrics.IncLocked()
+			p.logger.Warn("Destination object is locked, cannot overwrite",
+				zap.String("key", task.Key),
+				zap.String("dst_key", task.DestinationKey()),
+				zap.Error(err),
+			)
+			return
+		}
+
 		lastErr = err
 		p.logger.Warn("Task attempt failed",
 			zap.String("key", task.Key),
@@ -78,36 +276,483 @@ func (p *TaskProcessor) Process(ctx context.Context, task Task) {
 
 		if attempt < p.config.Retries {
 			backoff := p.calculateBackoff(attempt)
-			time.Sleep(backoff)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
 		}
 	}
 
+	p.logIfSlow(task, startTime, attempts)
+
+	// An object past its budget is abandoned, not retried later
+	if ctx.Err() != nil && runCtx.Err() == nil {
+		lastErr = fmt.Errorf("object timeout: not migrated within %s: %w", p.config.ObjectTimeout, lastErr)
+		p.markFailed(task, lastErr)
+		p.metrics.IncFailed(storage.ErrorCategory(lastErr), task.Size)
+		p.logger.Error("Task abandoned after exceeding object timeout",
+			zap.String("key", task.Key),
+			zap.Int("attempts", attempts),
+			zap.Error(lastErr),
+		)
+		return
+	}
+
+	// Give the object another round of attempts later instead of failing it
+	if p.deferred != nil && task.Deferrals < p.config.DeferredRetries && runCtx.Err() == nil {
+		p.deferred.schedule(runCtx, task, lastErr)
+		return
+	}
+
+	// A source that still cannot serve the object will not serve it on a later
+	// run either, so it is set aside without failing the migration
+	if p.config.SkipUnreadable && isSourceReadError(lastErr) && runCtx.Err() == nil {
+		p.markUnreadable(task, lastErr)
+		p.metrics.IncUnreadable()
+		p.logger.Warn("Skipping unreadable source object",
+			zap.String("key", task.Key),
+			zap.Int64("size", task.Size),
+			zap.Error(lastErr),
+		)
+		return
+	}
+
 	// Mark as failed
 	p.markFailed(task, lastErr)
-	p.metrics.IncFailed()
+	p.metrics.IncFailed(storage.ErrorCategory(lastErr), task.Size)
 	p.logger.Error("Task failed after all retries",
 		zap.String("key", task.Key),
 		zap.Error(lastErr),
 	)
 }
 
-func (p *TaskProcessor) processTask(ctx context.Context, task Task) error {
+// completedOnDestination reports whether a task recorded as completed is still
+// on the destination with the size of the task and the ETag of its upload.
+// The comparison uses the recorded destination ETag, so it also holds for
+// multipart uploads.
+func (p *TaskProcessor) completedOnDestination(ctx context.Context, task Task, record *checkpoint.TaskRecord) bool {
+	info, err := p.dstClient.HeadObject(ctx, task.DestinationBucket(), task.DestinationKey())
+	if err != nil {
+		p.logger.Info("Completed task not found on destination, re-migrating",
+			zap.String("key", task.Key),
+			zap.String("dst_key", task.DestinationKey()),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	uploaded, stored := strings.Trim(record.DstETag, `"`), strings.Trim(info.ETag, `"`)
+	if info.Size != task.Size || (uploaded != "" && !strings.EqualFold(uploaded, stored)) {
+		p.logger.Info("Completed task differs on destination, re-migrating",
+			zap.String("key", task.Key),
+			zap.String("dst_key", task.DestinationKey()),
+			zap.Int64("size", task.Size),
+			zap.Int64("dst_size", info.Size),
+			zap.String("recorded_etag", uploaded),
+			zap.String("dst_etag", stored),
+		)
+		return false
+	}
+	return true
+}
+
+// categoryPanic and categoryQuarantined are the failure categories of tasks
+// that panicked and of quarantined tasks
+const (
+	categoryPanic       = "panic"
+	categoryQuarantined = "quarantined"
+)
+
+// recoverPanic turns a panic while handling task into a failure of the task,
+// so that one poison object cannot crash the whole migration. It must be
+// deferred directly.
+func (p *TaskProcessor) recoverPanic(task Task) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	err := fmt.Errorf("panic while migrating %s: %v", task.Key, r)
+	p.logger.Error("Recovered from panic, marking task failed",
+		zap.String("key", task.Key),
+		zap.Any("panic", r),
+		zap.Stack("stack"),
+	)
+	p.markFailed(task, err)
+	p.metrics.IncFailed(categoryPanic, task.Size)
+}
+
+// quarantined reports whether task has failed in MaxTaskAttempts runs in a
+// row and is skipped instead of attempted again, quarantining it in the
+// checkpoint. A changed source object is attempted again.
+func (p *TaskProcessor) quarantined(task Task, record *checkpoint.TaskRecord) bool {
+	if p.config.MaxTaskAttempts <= 0 || record.Attempts < p.config.MaxTaskAttempts {
+		return false
+	}
+	if record.Status != checkpoint.StatusFailed && record.Status != checkpoint.StatusQuarantine {
+		return false
+	}
+	if record.Size != task.Size || record.ETag != task.ETag {
+		return false
+	}
+
+	if record.Status != checkpoint.StatusQuarantine {
+		quarantine := *record
+		quarantine.Status = checkpoint.StatusQuarantine
+		if err := p.saveRecord(&quarantine); err != nil {
+			p.logger.Error("Failed to save quarantined task",
+				zap.String("bucket", task.Bucket),
+				zap.String("key", task.Key),
+				zap.Error(err))
+		}
+	}
+
+	p.logger.Warn("Skipping quarantined task that failed in too many runs",
+		zap.String("key", task.Key),
+		zap.Int("attempts", record.Attempts),
+		zap.String("last_error", record.LastError),
+	)
+	p.metrics.IncFailed(categoryQuarantined, task.Size)
+	return true
+}
+
+// packs reports whether task is packed into an archive instead of being
+// uploaded on its own. Byte ranges are always uploaded on their own.
+func (p *TaskProcessor) packs(task Task) bool {
+	return p.packer != nil && task.Range == nil && task.Size < p.config.PackThreshold
+}
+
+// logIfSlow emits a warning when an object's migration took longer than SlowThreshold
+func (p *TaskProcessor) logIfSlow(task Task, startTime time.Time, attempts int) {
+	if p.config.SlowThreshold <= 0 {
+		return
+	}
+
+	elapsed := time.Since(startTime)
+	if elapsed <= p.config.SlowThreshold {
+		return
+	}
+
+	p.logger.Warn("Slow object migration",
+		zap.String("key", task.Key),
+		zap.Int64("size", task.Size),
+		zap.Duration("duration", elapsed),
+		zap.Int("attempts", attempts),
+	)
+}
+
+// ErrChecksumMismatch is reported when an uploaded object's checksum differs
+// from the source
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checkUploadETag warns when the ETag returned for an upload differs from the
+// source ETag and returns an ErrChecksumMismatch error. Only plain MD5 ETags of
+// whole objects are comparable; multipart ETags ("<md5>-<parts>") depend on the
+// part layout and are not checked.
+func (p *TaskProcessor) checkUploadETag(task Task, dstETag string) error {
+	src, dst := strings.Trim(task.ETag, `"`), strings.Trim(dstETag, `"`)
+	if task.Range != nil || src == "" || dst == "" || strings.Contains(src, "-") || strings.Contains(dst, "-") {
+		return nil
+	}
+	if strings.EqualFold(src, dst) {
+		return nil
+	}
+
+	p.logger.Warn("Destination ETag differs from source after upload, object may be corrupted or transformed by the server",
+		zap.String("key", task.Key),
+		zap.String("dst_key", task.DestinationKey()),
+		zap.String("src_etag", src),
+		zap.String("dst_etag", dst),
+	)
+	return fmt.Errorf("upload of %s: source etag %s, destination etag %s: %w", task.Key, src, dst, ErrChecksumMismatch)
+}
+
+// errIdentical is reported by a transfer when a conditional request showed that
+// the destination already holds the object's data
+var errIdentical = errors.New("destination already holds an identical object")
+
+// processTask runs one attempt of task and returns the ETag of the uploaded object
+func (p *TaskProcessor) processTask(ctx context.Context, task Task) (string, error) {
+	if p.tracksProgress() {
+		p.metrics.StartWorkerTask(p.id, task.Key, task.Size)
+	}
+
+	// Bound the attempt even while data keeps moving
+	parent := ctx
+	if p.config.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.AttemptTimeout)
+		defer cancel()
+	}
+
+	// Fail the attempt instead of pinning the worker when data stops moving
+	var watchdog *idleWatchdog
+	if p.config.IdleTimeout > 0 {
+		ctx, watchdog = newIdleWatchdog(ctx, p.config.IdleTimeout)
+		defer watchdog.Stop()
+	}
+
+	content := newContentHash(p.config.VerifyContent)
+	etag, err := p.transfer(ctx, task, watchdog, content)
+	if err = watchdog.Err(err); err != nil {
+		return "", p.attemptErr(parent, ctx, err)
+	}
+	if p.config.VerifyAfterPut {
+		if err := p.verifyWrite(ctx, task, etag); err != nil {
+			return "", p.attemptErr(parent, ctx, err)
+		}
+	}
+	if p.config.VerifyKeys {
+		if err := p.verifyKey(ctx, task); err != nil {
+			return "", p.attemptErr(parent, ctx, err)
+		}
+	}
+	if content != nil {
+		if err := p.verifyContent(ctx, task, content); err != nil {
+			return "", p.attemptErr(parent, ctx, err)
+		}
+	}
+	return etag, nil
+}
+
+// attemptErr annotates err when the attempt ran out of AttemptTimeout, so that
+// it is retried as a timeout. An attempt cut short by its parent (the object
+// budget or the run) keeps err unchanged.
+func (p *TaskProcessor) attemptErr(parent, ctx context.Context, err error) error {
+	if p.config.AttemptTimeout <= 0 || parent.Err() != nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("attempt timeout: not finished within %s: %w", p.config.AttemptTimeout, err)
+}
+
+// ErrWriteNotVerified is reported when the destination does not show an
+// uploaded object as written; the upload is retried
+var ErrWriteNotVerified = errors.New("upload not confirmed by destination")
+
+// verifyWrite HEADs the uploaded object and checks that it has the size of the
+// task and the ETag the upload returned, catching writes the destination
+// acknowledged but dropped
+func (p *TaskProcessor) verifyWrite(ctx context.Context, task Task, etag string) error {
+	info, err := p.dstClient.HeadObject(ctx, task.DestinationBucket(), task.DestinationKey())
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return fmt.Errorf("upload of %s: object missing on destination: %w", task.Key, ErrWriteNotVerified)
+		}
+		return fmt.Errorf("failed to verify upload of %s: %w", task.Key, err)
+	}
+
+	if info.Size != task.Size {
+		return fmt.Errorf("upload of %s: destination size %d, expected %d: %w", task.Key, info.Size, task.Size, ErrWriteNotVerified)
+	}
+	uploaded, stored := strings.Trim(etag, `"`), strings.Trim(info.ETag, `"`)
+	if uploaded != "" && stored != "" && !strings.EqualFold(uploaded, stored) {
+		return fmt.Errorf("upload of %s: destination etag %s, upload returned %s: %w", task.Key, stored, uploaded, ErrWriteNotVerified)
+	}
+	return nil
+}
+
+// ErrKeyMismatch is reported when an uploaded object is not listed under its
+// exact key, e.g. because the destination decoded "+" or "%" in the request
+// path differently. Uploading again would land on the same key, so it is not
+// retried.
+var ErrKeyMismatch = errors.New("destination key differs from source key")
+
+// verifyKey lists the destination from the uploaded key and checks that an
+// object with exactly that key exists. A HEAD cannot tell, since its path is
+// decoded the same way as the upload's.
+func (p *TaskProcessor) verifyKey(ctx context.Context, task Task) error {
+	key := task.DestinationKey()
+
+	listCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	objCh, errCh := p.dstClient.ListObjects(listCtx, task.DestinationBucket(), key, storage.ListOptions{})
+	for {
+		select {
+		case obj, ok := <-objCh:
+			if !ok {
+				if err := <-errCh; err != nil {
+					return fmt.Errorf("failed to verify key of %s: %w", task.Key, err)
+				}
+				return fmt.Errorf("upload of %s: no object listed under key %q: %w", task.Key, key, ErrKeyMismatch)
+			}
+			if obj.Key == key {
+				return nil
+			}
+			// Listings are in key order, so the key cannot follow
+			if obj.Key > key {
+				return fmt.Errorf("upload of %s: no object listed under key %q: %w", task.Key, key, ErrKeyMismatch)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (p *TaskProcessor) transfer(ctx context.Context, task Task, watchdog *idleWatchdog, content *contentHash) (string, error) {
+	// The source stream stays open until the upload completes, so the read
+	// slot is held for the whole transfer
+	if err := p.acquireRead(ctx); err != nil {
+		return "", err
+	}
+	defer p.releaseRead()
+
+	grants, err := p.sourceGrants(ctx, task)
+	if err != nil {
+		return "", err
+	}
+	task.Grants = grants
+
+	if task.Tags, err = p.sourceTags(ctx, task); err != nil {
+		return "", err
+	}
+
 	// Get source object
-	srcObj, err := p.srcClient.GetObject(ctx, task.Bucket, task.Key)
+	var srcObj storage.Object
+	if task.Range != nil {
+		srcObj, err = p.srcClient.GetObjectRange(ctx, task.Bucket, task.Key, task.Range.Offset, task.Size, task.ETag)
+	} else {
+		// Reading against the ETag of a mismatching destination object
+		// transfers nothing when the source turns out to hold the same data
+		var opts storage.GetOptions
+		if p.config.Conditional {
+			opts.IfNoneMatch = task.DstETag
+		}
+		srcObj, err = p.srcClient.GetObject(ctx, task.Bucket, task.Key, opts)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get source object: %w", err)
+		if storage.IsNotModified(err) {
+			return "", errIdentical
+		}
+		return "", &sourceReadError{err: fmt.Errorf("failed to get source object: %w", err)}
+	}
+
+	// Encoded objects (e.g. Content-Encoding: gzip) are copied as opaque
+	// bytes, so the header has to travel with them. A byte range of encoded
+	// data is not itself decodable, so ranges never carry it.
+	if task.Range == nil {
+		info, err := srcObj.Stat()
+		if err != nil {
+			srcObj.Close()
+			if storage.IsNotModified(err) {
+				return "", errIdentical
+			}
+			return "", &sourceReadError{err: fmt.Errorf("failed to get source object: %w", err)}
+		}
+		task.ContentEncoding = info.ContentEncoding
 	}
-	defer srcObj.Close()
 
 	// Choose upload strategy based on size
-	if task.Size < p.config.MultipartThreshold {
-		return p.uploadSingle(ctx, task, srcObj)
+	if !p.useMultipart(task.Size) {
+		defer srcObj.Close()
+		return p.uploadSingle(ctx, task, watchdog.Reader(p.progressReader(content.Reader(p.bandwidth.Reader(ctx, srcObj)))), p.config.NoMultipart)
+	}
+
+	// A dropped source stream is resumed from the current offset rather than
+	// failing the whole multipart upload
+	reader := &resumableReader{
+		ctx:     ctx,
+		client:  p.srcClient,
+		task:    task,
+		current: srcObj,
+		retries: p.config.Retries,
+		backoff: p.calculateBackoff,
+		logger:  p.logger,
 	}
+	defer reader.Close()
 
-	return p.uploadMultipart(ctx, task, srcObj)
+	return p.uploadMultipart(ctx, task, watchdog.Reader(p.progressReader(content.Reader(p.bandwidth.Reader(ctx, reader)))), watchdog)
 }
 
-func (p *TaskProcessor) uploadSingle(ctx context.Context, task Task, reader io.Reader) error {
+// sourceGrants returns the ACL grants of the source object to apply on the
+// destination when CopyACL is set. Grants to the source owner are left out,
+// since the destination object is owned by the uploading credentials. Grants
+// that cannot be set on upload (e.g. WRITE, which has no object-level header)
+// are logged and counted instead of being dropped silently.
+func (p *TaskProcessor) sourceGrants(ctx context.Context, task Task) ([]storage.Grant, error) {
+	if !p.config.CopyACL {
+		return nil, nil
+	}
+
+	acl, err := p.srcClient.GetObjectACL(ctx, task.Bucket, task.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source ACL: %w", err)
+	}
+
+	var grants []storage.Grant
+	for _, grant := range acl.Grants {
+		if grant.GranteeType == storage.GranteeCanonicalUser && grant.Grantee == acl.Owner {
+			continue
+		}
+		if !grant.CanApply() {
+			p.logger.Warn("Cannot apply source ACL grant on destination, dropping it",
+				zap.String("key", task.Key),
+				zap.Stringer("grant", grant),
+			)
+			p.metrics.IncACLGrantDropped()
+			continue
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// acquireRead waits for a source read slot when --max-source-reads is set
+func (p *TaskProcessor) acquireRead(ctx context.Context) error {
+	if p.reads == nil {
+		return nil
+	}
+	return p.reads.Acquire(ctx)
+}
+
+// releaseRead frees the slot taken by acquireRead
+func (p *TaskProcessor) releaseRead() {
+	if p.reads != nil {
+		p.reads.Release()
+	}
+}
+
+// tracksProgress reports whether this processor publishes its current object
+// for the per-worker progress display
+func (p *TaskProcessor) tracksProgress() bool {
+	return p.config.VerboseProgress && p.id >= 0
+}
+
+// progressReader wraps r so that bytes read from the source are published as
+// the worker's progress on its current object
+func (p *TaskProcessor) progressReader(r io.Reader) io.Reader {
+	if !p.tracksProgress() {
+		return r
+	}
+	return &workerProgressReader{reader: r, id: p.id, metrics: p.metrics}
+}
+
+type workerProgressReader struct {
+	reader  io.Reader
+	id      int
+	metrics *metrics.Collector
+}
+
+func (r *workerProgressReader) Read(b []byte) (int, error) {
+	n, err := r.reader.Read(b)
+	if n > 0 {
+		r.metrics.AddWorkerBytes(r.id, int64(n))
+	}
+	return n, err
+}
+
+// useMultipart reports whether an object of the given size should be uploaded
+// in parts. Objects below MultipartMinSize always use a single PUT, even when
+// MultipartThreshold is lower.
+func (p *TaskProcessor) useMultipart(size int64) bool {
+	if p.config.NoMultipart {
+		return false
+	}
+	return size >= p.config.MultipartThreshold && size >= p.config.MultipartMinSize
+}
+
+// uploadSingle uploads the object with one PutObject call. With forceSingle set
+// the client is not allowed to switch to multipart on its own for large objects.
+func (p *TaskProcessor) uploadSingle(ctx context.Context, task Task, reader io.Reader, forceSingle bool) (string, error) {
 	// Use original content-type if available, otherwise fallback to application/octet-stream
 	contentType := task.ContentType
 	if contentType == "" {
@@ -115,14 +760,34 @@ func (p *TaskProcessor) uploadSingle(ctx context.Context, task Task, reader io.R
 	}
 
 	opts := storage.PutOptions{
-		ContentType: contentType,
-		Metadata:    task.Metadata,
+		ContentType:      contentType,
+		ContentEncoding:  task.ContentEncoding,
+		Metadata:         p.uploadMetadata(task.Metadata, task.LastModified),
+		Grants:           task.Grants,
+		Tags:             p.uploadTags(task),
+		DisableMultipart: forceSingle,
+	}
+	// The destination rejects the upload when it already holds an object with
+	// the source ETag. Only plain MD5 ETags of whole objects are comparable.
+	conditional := p.config.Conditional && task.Range == nil && task.ETag != "" && !strings.Contains(task.ETag, "-")
+	if conditional {
+		opts.IfNoneMatch = task.ETag
 	}
 
-	return p.dstClient.PutObject(ctx, task.Bucket, task.Key, reader, task.Size, opts)
+	etag, err := p.dstClient.PutObject(ctx, task.DestinationBucket(), task.DestinationKey(), reader, task.Size, opts)
+	if conditional && storage.IsPreconditionFailed(err) {
+		return "", errIdentical
+	}
+	if len(opts.Tags) > 0 && storage.IsNotImplemented(err) {
+		p.tags.disable("destination", err)
+		return "", errTagsUnsupported
+	}
+	return etag, err
 }
 
-func (p *TaskProcessor) uploadMultipart(ctx context.Context, task Task, reader io.Reader) error {
+// uploadMultipart uploads the object in parts. Part buffers are wrapped by the
+// watchdog (which may be nil) so that a stalled part upload is detected too.
+func (p *TaskProcessor) uploadMultipart(ctx context.Context, task Task, reader io.Reader, watchdog *idleWatchdog) (string, error) {
 	// Use original content-type if available, otherwise fallback to application/octet-stream
 	contentType := task.ContentType
 	if contentType == "" {
@@ -130,73 +795,484 @@ func (p *TaskProcessor) uploadMultipart(ctx context.Context, task Task, reader i
 	}
 
 	opts := storage.PutOptions{
-		ContentType: contentType,
-		Metadata:    task.Metadata,
+		ContentType:     contentType,
+		ContentEncoding: task.ContentEncoding,
+		Metadata:        p.uploadMetadata(task.Metadata, task.LastModified),
+		Grants:          task.Grants,
+		Tags:            p.uploadTags(task),
 	}
 
 	// Initiate multipart upload
-	uploadID, err := p.dstClient.NewMultipartUpload(ctx, task.Bucket, task.Key, opts)
+	uploadID, err := p.dstClient.NewMultipartUpload(ctx, task.DestinationBucket(), task.DestinationKey(), opts)
+	if len(opts.Tags) > 0 && storage.IsNotImplemented(err) {
+		// Tell a destination without tagging apart from one without multipart
+		p.tags.disable("destination", err)
+		opts.Tags = nil
+		uploadID, err = p.dstClient.NewMultipartUpload(ctx, task.DestinationBucket(), task.DestinationKey(), opts)
+	}
+	if err != nil && storage.IsNotImplemented(err) {
+		// Minimal S3 servers may not implement multipart; nothing has been read
+		// from the source yet, so the whole object can still go in one PUT.
+		p.logger.Warn("Destination does not support multipart upload, falling back to single PUT",
+			zap.String("key", task.Key),
+			zap.Int64("size", task.Size),
+			zap.Error(err),
+		)
+		return p.uploadSingle(ctx, task, reader, true)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to initiate multipart upload: %w", err)
+		return "", fmt.Errorf("failed to initiate multipart upload: %w", err)
 	}
 
-	// Calculate number of parts
+	parts, err := p.uploadParts(ctx, task, uploadID, reader, watchdog)
+	if err != nil {
+		p.dstClient.AbortMultipartUpload(ctx, task.DestinationBucket(), task.DestinationKey(), uploadID)
+		return "", err
+	}
+	partCount := int(math.Ceil(float64(task.Size) / float64(p.config.PartSize)))
+
+	// A part the destination acknowledged without an ETag would otherwise
+	// surface as an obscure CompleteMultipartUpload error
+	if err := validateParts(parts, partCount); err != nil {
+		p.dstClient.AbortMultipartUpload(ctx, task.DestinationBucket(), task.DestinationKey(), uploadID)
+		return "", fmt.Errorf("multipart upload of %s: %w", task.Key, err)
+	}
+
+	// Complete multipart upload
+	return p.dstClient.CompleteMultipartUpload(ctx, task.DestinationBucket(), task.DestinationKey(), uploadID, parts)
+}
+
+// uploadParts uploads the parts of a multipart upload, up to PartConcurrency at
+// once, and returns them sorted by part number. Parts are read from reader in
+// order, and a part is only read once an upload slot is free, so at most
+// PartConcurrency parts are buffered. The first failure cancels the uploads
+// still running and is returned.
+func (p *TaskProcessor) uploadParts(ctx context.Context, task Task, uploadID string, reader io.Reader, watchdog *idleWatchdog) ([]storage.CompletedPart, error) {
 	partCount := int(math.Ceil(float64(task.Size) / float64(p.config.PartSize)))
+	concurrency := p.config.PartConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
 	parts := make([]storage.CompletedPart, 0, partCount)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+	}
 
-	// Upload parts
+	slots := make(chan struct{}, concurrency)
 	for partNum := 1; partNum <= partCount; partNum++ {
+		select {
+		case slots <- struct{}{}:
+		case <-partCtx.Done():
+		}
+		if partCtx.Err() != nil {
+			break
+		}
+
 		partSize := p.config.PartSize
 		if int64(partNum-1)*p.config.PartSize+partSize > task.Size {
 			partSize = task.Size - int64(partNum-1)*p.config.PartSize
 		}
 
 		// Read part data
-		partData := make([]byte, partSize)
+		partReader, n, cleanup, err := p.readPart(reader, partSize)
+		if err != nil {
+			<-slots
+			fail(fmt.Errorf("failed to read part %d: %w", partNum, err))
+			break
+		}
+
+		// Upload part
+		wg.Add(1)
+		go func(partNum int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer cleanup()
+
+			etag, err := p.dstClient.UploadPart(partCtx, task.DestinationBucket(), task.DestinationKey(), uploadID, partNum, watchdog.Reader(partReader), n)
+			if err != nil {
+				fail(fmt.Errorf("failed to upload part %d: %w", partNum, err))
+				return
+			}
+
+			mu.Lock()
+			parts = append(parts, storage.CompletedPart{
+				PartNumber: partNum,
+				ETag:       etag,
+			})
+			mu.Unlock()
+		}(partNum)
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// ErrInvalidParts is reported when the parts collected for a multipart upload
+// cannot be completed; the upload is aborted and retried
+var ErrInvalidParts = errors.New("invalid multipart upload parts")
+
+// validateParts checks that parts holds partCount parts numbered 1 to
+// partCount in order, each with an ETag
+func validateParts(parts []storage.CompletedPart, partCount int) error {
+	if len(parts) != partCount {
+		return fmt.Errorf("%d parts uploaded, expected %d: %w", len(parts), partCount, ErrInvalidParts)
+	}
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return fmt.Errorf("part %d has part number %d: %w", i+1, part.PartNumber, ErrInvalidParts)
+		}
+		if strings.Trim(part.ETag, `"`) == "" {
+			return fmt.Errorf("part %d has an empty etag: %w", part.PartNumber, ErrInvalidParts)
+		}
+	}
+	return nil
+}
+
+// readPart reads up to size bytes of the next part, either into memory or,
+// for parts above the spill threshold, into a temp file under SpillDir.
+// With StreamParts the part is read from reader while it is uploaded instead.
+// The returned cleanup func releases the part and must always be called.
+func (p *TaskProcessor) readPart(reader io.Reader, size int64) (io.Reader, int64, func(), error) {
+	// A streamed part cannot be sent again, so the client library does not
+	// retry it; a failed part fails the attempt and the object is retried
+	if p.config.StreamParts {
+		return &partStream{reader: reader, remaining: size}, size, func() {}, nil
+	}
+
+	if p.config.SpillDir == "" || size <= p.config.SpillThreshold {
+		partData, release := p.partBuffer(size)
 		n, err := io.ReadFull(reader, partData)
 		if err != nil && err != io.ErrUnexpectedEOF {
-			p.dstClient.AbortMultipartUpload(ctx, task.Bucket, task.Key, uploadID)
-			return fmt.Errorf("failed to read part %d: %w", partNum, err)
+			release()
+			return nil, 0, nil, err
 		}
-		partData = partData[:n]
+		return bytes.NewReader(partData[:n]), int64(n), release, nil
+	}
 
-		// Upload part
-		etag, err := p.dstClient.UploadPart(ctx, task.Bucket, task.Key, uploadID, partNum,
-			bytes.NewReader(partData), int64(len(partData)))
+	f, err := os.CreateTemp(p.config.SpillDir, "part-*")
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to create spill file: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+
+	n, err := io.CopyN(f, reader, size)
+	if err != nil && err != io.EOF {
+		cleanup()
+		return nil, 0, nil, err
+	}
+	if n == 0 {
+		cleanup()
+		return nil, 0, nil, io.EOF
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, 0, nil, fmt.Errorf("failed to rewind spill file: %w", err)
+	}
+
+	return f, n, cleanup, nil
+}
+
+// partStream reads the next size bytes of the source stream for a streamed
+// part, reporting a source that ends early as io.ErrUnexpectedEOF so that the
+// upload fails instead of sending a short part
+type partStream struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func (r *partStream) Read(b []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > r.remaining {
+		b = b[:r.remaining]
+	}
+	n, err := r.reader.Read(b)
+	r.remaining -= int64(n)
+	if err == io.EOF && r.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	} else if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// partBuffer returns a buffer of size bytes and the func releasing it, taking
+// it from the shared pool when buffers are pooled
+func (p *TaskProcessor) partBuffer(size int64) ([]byte, func()) {
+	if p.buffers == nil || size > p.config.PartSize {
+		return make([]byte, size), func() {}
+	}
+	buf := p.buffers.Get().(*[]byte)
+	return (*buf)[:size], func() { p.buffers.Put(buf) }
+}
+
+// expiresSoon reports whether the source lifecycle rules expire task's object
+// within SkipExpiringWithin, counting it as expiring when they do. Listings do
+// not carry the expiration, so the source object is read with a HEAD; if that
+// fails the object is migrated.
+func (p *TaskProcessor) expiresSoon(ctx context.Context, task Task) bool {
+	if p.config.SkipExpiringWithin <= 0 {
+		return false
+	}
+
+	info, err := p.srcClient.HeadObject(ctx, task.Bucket, task.Key)
+	if err != nil {
+		p.logger.Debug("Failed to read source expiration, migrating object",
+			zap.String("key", task.Key),
+			zap.Error(err),
+		)
+		return false
+	}
+	if info.Expiration.IsZero() || time.Until(info.Expiration) > p.config.SkipExpiringWithin {
+		return false
+	}
+
+	p.logger.Info("Skipping object that expires soon on the source",
+		zap.String("key", task.Key),
+		zap.Time("expiration", info.Expiration),
+	)
+	p.metrics.IncExpiring()
+	return true
+}
+
+// objectExistsAndMatches checks whether the destination already holds the
+// object, returning the destination's object info when it does. Only a
+// missing object (or a destination that cannot be checked at all, such as the
+// HTTP sink) means the object is transferred; any other failed check is
+// returned as an error rather than mistaken for an absent object.
+func (p *TaskProcessor) objectExistsAndMatches(ctx context.Context, task Task) (storage.ObjectInfo, bool, error) {
+	info, err := p.dstClient.HeadObject(ctx, task.DestinationBucket(), task.DestinationKey())
+	if err != nil {
+		switch {
+		case storage.IsNotFound(err) || storage.IsNotImplemented(err):
+			return info, false, nil
+		case storage.IsAccessDenied(err):
+			return info, false, fmt.Errorf("access denied checking destination object %s/%s, check the target credentials' permissions: %w",
+				task.DestinationBucket(), task.DestinationKey(), err)
+		default:
+			return info, false, fmt.Errorf("failed to check destination object %s/%s: %w",
+				task.DestinationBucket(), task.DestinationKey(), err)
+		}
+	}
+
+	if p.config.CopyIfNewer && !task.LastModified.IsZero() {
+		return info, !isNewer(task.LastModified, info.LastModified, p.config.MtimeSkewTolerance), nil
+	}
+
+	if p.config.CompareSize && info.Size != task.Size {
+		return info, false, nil
+	}
+	// The etag of a range copy never matches the etag of the whole source object
+	if p.config.CompareETag && task.Range == nil && info.ETag != task.ETag {
+		return info, false, nil
+	}
+	if len(p.config.CompareMetadata) > 0 {
+		return info, p.metadataMatches(ctx, task, info), nil
+	}
+	return info, true, nil
+}
+
+// metadataMatches reports whether the compared user metadata keys hold the
+// same values on both sides. A key missing on the source cannot confirm the
+// copy, so the object is transferred again.
+func (p *TaskProcessor) metadataMatches(ctx context.Context, task Task, dstInfo storage.ObjectInfo) bool {
+	srcMetadata := task.Metadata
+	if srcMetadata == nil {
+		// Listings do not carry user metadata, so the source is read with a HEAD
+		srcInfo, err := p.srcClient.HeadObject(ctx, task.Bucket, task.Key)
 		if err != nil {
-			p.dstClient.AbortMultipartUpload(ctx, task.Bucket, task.Key, uploadID)
-			return fmt.Errorf("failed to upload part %d: %w", partNum, err)
+			p.logger.Warn("Failed to read source metadata for comparison",
+				zap.String("key", task.Key),
+				zap.Error(err),
+			)
+			return false
 		}
+		srcMetadata = srcInfo.Metadata
+	}
 
-		parts = append(parts, storage.CompletedPart{
-			PartNumber: partNum,
-			ETag:       etag,
-		})
+	for _, key := range p.config.CompareMetadata {
+		src, ok := metadataValue(srcMetadata, key)
+		if !ok {
+			return false
+		}
+		if dst, ok := metadataValue(dstInfo.Metadata, key); !ok || dst != src {
+			return false
+		}
 	}
+	return true
+}
 
-	// Complete multipart upload
-	return p.dstClient.CompleteMultipartUpload(ctx, task.Bucket, task.Key, uploadID, parts)
+// metadataValue looks up a user metadata key case-insensitively, with or
+// without the x-amz-meta- prefix
+func metadataValue(metadata map[string]string, key string) (string, bool) {
+	for k, v := range metadata {
+		if strings.TrimPrefix(strings.ToLower(k), "x-amz-meta-") == key {
+			return v, true
+		}
+	}
+	return "", false
 }
 
-func (p *TaskProcessor) objectExistsAndMatches(ctx context.Context, task Task) bool {
-	info, err := p.dstClient.HeadObject(ctx, task.Bucket, task.Key)
+// syncMetadata brings the content type and user metadata of an object whose
+// data already matches on the destination in line with the source, using a
+// metadata-only server-side copy. It reports whether the object is done; on
+// false the object is transferred in full instead.
+func (p *TaskProcessor) syncMetadata(ctx context.Context, task Task, dstInfo storage.ObjectInfo) bool {
+	// Listings do not carry user metadata, so the source is read with a HEAD
+	srcInfo, err := p.srcClient.HeadObject(ctx, task.Bucket, task.Key)
+	if err != nil {
+		p.logger.Warn("Failed to read source metadata, transferring object instead",
+			zap.String("key", task.Key),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	contentType := srcInfo.ContentType
+	if override, ok := p.config.ContentTypes[strings.ToLower(path.Ext(task.Key))]; ok {
+		contentType = override
+	}
+
+	metadata := p.uploadMetadata(srcInfo.Metadata, srcInfo.LastModified)
+	if contentType == dstInfo.ContentType && srcInfo.ContentEncoding == dstInfo.ContentEncoding &&
+		metadataEqual(metadata, dstInfo.Metadata) {
+		p.logger.Debug("Skipping existing object with matching metadata", zap.String("key", task.Key))
+		p.markCompleted(task, dstInfo.ETag)
+		p.metrics.IncSkippedWithBytes(task.Size)
+		return true
+	}
+
+	// The copy replaces the destination ACL as well
+	grants, err := p.sourceGrants(ctx, task)
 	if err != nil {
+		p.logger.Warn("Failed to read source ACL, transferring object instead",
+			zap.String("key", task.Key),
+			zap.Error(err),
+		)
 		return false
 	}
 
-	return info.Size == task.Size && info.ETag == task.ETag
+	opts := storage.PutOptions{
+		ContentType:     contentType,
+		ContentEncoding: srcInfo.ContentEncoding,
+		Metadata:        metadata,
+		Grants:          grants,
+	}
+	if err := p.dstClient.UpdateMetadata(ctx, task.DestinationBucket(), task.DestinationKey(), dstInfo.ETag, opts); err != nil {
+		p.logger.Warn("Metadata-only copy failed, transferring object instead",
+			zap.String("key", task.Key),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	p.logger.Info("Updated destination metadata",
+		zap.String("key", task.Key),
+		zap.String("content_type", contentType),
+	)
+	// The copy gives the object a new ETag, which is not returned here
+	p.markCompleted(task, "")
+	p.metrics.IncMetadataUpdated()
+	return true
 }
 
-func (p *TaskProcessor) markCompleted(task Task) {
+// originalMtimeKey is the user metadata key holding the source Last-Modified
+// with PreserveMtime. S3 sets Last-Modified to the upload time and offers no
+// way to override it, so the original is kept where it can be recovered.
+const originalMtimeKey = "original-mtime"
+
+// uploadMetadata returns the user metadata to upload for a source object last
+// modified at lastModified. With PreserveMtime it adds originalMtimeKey, in
+// RFC 3339 with second precision as HEAD responses report it, to a copy of
+// metadata; otherwise metadata is returned unchanged.
+func (p *TaskProcessor) uploadMetadata(metadata map[string]string, lastModified time.Time) map[string]string {
+	if !p.config.PreserveMtime || lastModified.IsZero() {
+		return metadata
+	}
+
+	withMtime := make(map[string]string, len(metadata)+1)
+	for k, v := range metadata {
+		if strings.TrimPrefix(strings.ToLower(k), "x-amz-meta-") != originalMtimeKey {
+			withMtime[k] = v
+		}
+	}
+	withMtime[originalMtimeKey] = lastModified.UTC().Truncate(time.Second).Format(time.RFC3339)
+	return withMtime
+}
+
+// metadataEqual compares user metadata, ignoring the letter case of keys
+func metadataEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	folded := make(map[string]string, len(b))
+	for k, v := range b {
+		folded[strings.ToLower(k)] = v
+	}
+	for k, v := range a {
+		if w, ok := folded[strings.ToLower(k)]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// isNewer reports whether src is newer than dst by more than tolerance.
+// Timestamps within the tolerance (inclusive) are treated as equal, so clock
+// skew between hosts cannot cause an unchanged object to be recopied.
+func isNewer(src, dst time.Time, tolerance time.Duration) bool {
+	return src.Sub(dst) > tolerance
+}
+
+// saveRecord saves record to the checkpoint, or buffers it when the processor
+// is part of a small-object batch
+func (p *TaskProcessor) saveRecord(record *checkpoint.TaskRecord) error {
+	if p.records != nil {
+		p.records.add(record)
+		return nil
+	}
+	return p.checkpoint.SaveTask(record)
+}
+
+// markCompleted records task as completed along with the destination ETag,
+// if known
+func (p *TaskProcessor) markCompleted(task Task, dstETag string) {
 	record := &checkpoint.TaskRecord{
-		Bucket: task.Bucket,
-		Key:    task.Key,
-		Size:   task.Size,
-		ETag:   task.ETag,
-		Status: checkpoint.StatusCompleted,
+		Bucket:  task.Bucket,
+		Key:     task.CheckpointKey(),
+		Size:    task.Size,
+		ETag:    task.ETag,
+		DstETag: dstETag,
+		Status:  checkpoint.StatusCompleted,
 	}
 
-	if err := p.checkpoint.SaveTask(record); err != nil {
+	if err := p.saveRecord(record); err != nil {
 		p.logger.Error("Failed to save completed task",
 			zap.String("bucket", task.Bucket),
 			zap.String("key", task.Key),
@@ -205,16 +1281,24 @@ func (p *TaskProcessor) markCompleted(task Task) {
 }
 
 func (p *TaskProcessor) markFailed(task Task, err error) {
+	if p.config.OnFailure != nil {
+		p.config.OnFailure(task, err)
+	}
+	if p.config.TagFailedSource {
+		p.tagFailedSource(task)
+	}
+
 	record := &checkpoint.TaskRecord{
 		Bucket:    task.Bucket,
-		Key:       task.Key,
+		Key:       task.CheckpointKey(),
 		Size:      task.Size,
 		ETag:      task.ETag,
 		Status:    checkpoint.StatusFailed,
+		Attempts:  p.failedRuns(task) + 1,
 		LastError: err.Error(),
 	}
 
-	if saveErr := p.checkpoint.SaveTask(record); saveErr != nil {
+	if saveErr := p.saveRecord(record); saveErr != nil {
 		// Check if this is a database closed error
 		if strings.Contains(saveErr.Error(), "database is closed") {
 			p.logger.Warn("Cannot save failed task - database is closed",
@@ -230,11 +1314,121 @@ func (p *TaskProcessor) markFailed(task Task, err error) {
 	}
 }
 
+// failedRuns returns the number of earlier runs in a row in which task failed
+// for the same source object
+func (p *TaskProcessor) failedRuns(task Task) int {
+	record, err := p.checkpoint.GetTask(task.Bucket, task.CheckpointKey())
+	if err != nil || record == nil {
+		return 0
+	}
+	if record.Status != checkpoint.StatusFailed && record.Status != checkpoint.StatusQuarantine {
+		return 0
+	}
+	if record.Size != task.Size || record.ETag != task.ETag {
+		return 0
+	}
+	return record.Attempts
+}
+
+// The tag set on source objects that failed with TagFailedSource
+const (
+	failedTagKey   = "migration-status"
+	failedTagValue = "failed"
+)
+
+// tagTimeout bounds tagging a failed source object
+const tagTimeout = 30 * time.Second
+
+// tagFailedSource tags the source object of a failed task so that failures
+// can be queried on the source. Tagging replaces the whole tag set, so the
+// existing tags are kept. Errors are only logged, the task has failed anyway.
+func (p *TaskProcessor) tagFailedSource(task Task) {
+	// Tasks also fail when the run is interrupted, so the tag gets its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), tagTimeout)
+	defer cancel()
+
+	tags, err := p.srcClient.GetObjectTags(ctx, task.Bucket, task.Key)
+	if err != nil {
+		p.logger.Warn("Failed to read tags of failed source object",
+			zap.String("key", task.Key),
+			zap.Error(err))
+		return
+	}
+	if tags[failedTagKey] == failedTagValue {
+		return
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	tags[failedTagKey] = failedTagValue
+
+	if err := p.srcClient.PutObjectTags(ctx, task.Bucket, task.Key, tags); err != nil {
+		p.logger.Warn("Failed to tag failed source object",
+			zap.String("key", task.Key),
+			zap.Error(err))
+	}
+}
+
+func (p *TaskProcessor) markLocked(task Task, err error) {
+	record := &checkpoint.TaskRecord{
+		Bucket:    task.Bucket,
+		Key:       task.CheckpointKey(),
+		Size:      task.Size,
+		ETag:      task.ETag,
+		Status:    checkpoint.StatusLocked,
+		LastError: err.Error(),
+	}
+
+	if saveErr := p.saveRecord(record); saveErr != nil {
+		p.logger.Error("Failed to save locked task",
+			zap.String("bucket", task.Bucket),
+			zap.String("key", task.Key),
+			zap.Error(saveErr))
+	}
+}
+
+// sourceReadError marks a failure to read the source object, as opposed to a
+// failure writing the destination
+type sourceReadError struct {
+	err error
+}
+
+func (e *sourceReadError) Error() string { return e.err.Error() }
+func (e *sourceReadError) Unwrap() error { return e.err }
+
+func isSourceReadError(err error) bool {
+	var readErr *sourceReadError
+	return errors.As(err, &readErr)
+}
+
+// markUnreadable records a task skipped with SkipUnreadable. The record is not
+// failed, so retry-failed leaves it alone; a resumed run tries it again.
+func (p *TaskProcessor) markUnreadable(task Task, err error) {
+	record := &checkpoint.TaskRecord{
+		Bucket:    task.Bucket,
+		Key:       task.CheckpointKey(),
+		Size:      task.Size,
+		ETag:      task.ETag,
+		Status:    checkpoint.StatusUnreadable,
+		LastError: err.Error(),
+	}
+
+	if saveErr := p.saveRecord(record); saveErr != nil {
+		p.logger.Error("Failed to save unreadable task",
+			zap.String("bucket", task.Bucket),
+			zap.String("key", task.Key),
+			zap.Error(saveErr))
+	}
+}
+
 func (p *TaskProcessor) isRetriableError(err error) bool {
 	// More sophisticated error classification
 	if err == nil {
 		return false
 	}
+	if errors.Is(err, ErrWriteNotVerified) || errors.Is(err, ErrInvalidParts) || errors.Is(err, ErrChecksumMismatch) {
+		return true
+	}
 
 	errStr := strings.ToLower(err.Error())
 	// Check for network-related errors
@@ -0,0 +1,229 @@
+package worker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/metrics"
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// testMetrics is shared by all tests, since a collector registers its metrics
+// globally and can only be created once per process
+var testMetrics = metrics.New()
+
+const testBucket = "bucket"
+
+// testConfig returns a worker configuration with 5 MiB parts and multipart
+// uploads from 8 MiB
+func testConfig() Config {
+	return Config{
+		MultipartThreshold: 8 << 20,
+		PartSize:           5 << 20,
+		PartConcurrency:    1,
+		Retries:            1,
+		RetryBackoffMs:     1,
+	}
+}
+
+// newTestStore opens a checkpoint in a temporary directory
+func newTestStore(t *testing.T) *checkpoint.SQLiteStore {
+	t.Helper()
+	store, err := checkpoint.NewSQLiteStore(filepath.Join(t.TempDir(), "checkpoint.db"), checkpoint.SQLiteOptions{})
+	if err != nil {
+		t.Fatalf("open checkpoint: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newTestProcessor returns a worker of a pool migrating from src to dst
+func newTestProcessor(t *testing.T, config Config, src, dst storage.Client, store checkpoint.Store) *TaskProcessor {
+	t.Helper()
+	pool := NewPool(1, config, src, dst, store, testMetrics, zap.NewNop())
+	return pool.newProcessor(0, zap.NewNop())
+}
+
+// putSource stores data under key on src and returns the task migrating it.
+// Data larger than partSize is uploaded in parts, so that the source ETag is
+// the multipart ETag the migration reproduces with the same part size.
+func putSource(t *testing.T, src *storage.MemoryClient, key string, data []byte, partSize int64, opts storage.PutOptions) Task {
+	t.Helper()
+	ctx := context.Background()
+
+	var etag string
+	var err error
+	if int64(len(data)) <= partSize {
+		etag, err = src.PutObject(ctx, testBucket, key, bytes.NewReader(data), int64(len(data)), opts)
+	} else {
+		etag, err = putMultipart(ctx, src, key, data, partSize, opts)
+	}
+	if err != nil {
+		t.Fatalf("put source %s: %v", key, err)
+	}
+	return Task{Bucket: testBucket, Key: key, Size: int64(len(data)), ETag: etag, ContentType: opts.ContentType}
+}
+
+func putMultipart(ctx context.Context, client *storage.MemoryClient, key string, data []byte, partSize int64, opts storage.PutOptions) (string, error) {
+	uploadID, err := client.NewMultipartUpload(ctx, testBucket, key, opts)
+	if err != nil {
+		return "", err
+	}
+	var parts []storage.CompletedPart
+	for n, offset := 1, int64(0); offset < int64(len(data)); n, offset = n+1, offset+partSize {
+		part := data[offset:min(offset+partSize, int64(len(data)))]
+		etag, err := client.UploadPart(ctx, testBucket, key, uploadID, n, bytes.NewReader(part), int64(len(part)))
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, storage.CompletedPart{PartNumber: n, ETag: etag})
+	}
+	return client.CompleteMultipartUpload(ctx, testBucket, key, uploadID, parts)
+}
+
+// testData returns size bytes of a repeating pattern
+func testData(size int) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	return data
+}
+
+// assertStatus fails the test unless the checkpoint has key in status
+func assertStatus(t *testing.T, store checkpoint.Store, key string, status checkpoint.TaskStatus) *checkpoint.TaskRecord {
+	t.Helper()
+	record, err := store.GetTask(testBucket, key)
+	if err != nil {
+		t.Fatalf("get task %s: %v", key, err)
+	}
+	if record == nil {
+		t.Fatalf("task %s: no checkpoint record, want %s", key, status)
+	}
+	if record.Status != status {
+		t.Fatalf("task %s: status %s (%s), want %s", key, record.Status, record.LastError, status)
+	}
+	return record
+}
+
+func TestTransfer(t *testing.T) {
+	config := testConfig()
+	tests := []struct {
+		name      string
+		size      int
+		multipart bool
+	}{
+		{name: "single put", size: 64 << 10},
+		{name: "multipart", size: 12 << 20, multipart: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := storage.NewMemoryClient(testBucket)
+			dst := storage.NewMemoryClient(testBucket)
+			store := newTestStore(t)
+			data := testData(tt.size)
+			task := putSource(t, src, "dir/object", data, config.PartSize, storage.PutOptions{})
+
+			newTestProcessor(t, config, src, dst, store).Transfer(context.Background(), task)
+
+			record := assertStatus(t, store, task.Key, checkpoint.StatusCompleted)
+			got, err := dst.Data(testBucket, task.Key)
+			if err != nil {
+				t.Fatalf("read destination: %v", err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("destination data differs from source (%d bytes, want %d)", len(got), len(data))
+			}
+
+			info, err := dst.HeadObject(context.Background(), testBucket, task.Key)
+			if err != nil {
+				t.Fatalf("head destination: %v", err)
+			}
+			if info.ETag != task.ETag || record.DstETag != task.ETag {
+				t.Fatalf("destination etag %s (recorded %s), want source etag %s", info.ETag, record.DstETag, task.ETag)
+			}
+			if multipart := len(info.ETag) > 32 && info.ETag[32] == '-'; multipart != tt.multipart {
+				t.Fatalf("destination etag %s: multipart %v, want %v", info.ETag, multipart, tt.multipart)
+			}
+			if !tt.multipart {
+				sum := md5.Sum(data)
+				if want := hex.EncodeToString(sum[:]); info.ETag != want {
+					t.Fatalf("destination etag %s, want md5 %s", info.ETag, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsNewer(t *testing.T) {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tolerance := 2 * time.Second
+	tests := []struct {
+		name      string
+		src       time.Time
+		tolerance time.Duration
+		want      bool
+	}{
+		{name: "equal", src: base, tolerance: tolerance, want: false},
+		{name: "equal without tolerance", src: base, want: false},
+		{name: "newer within tolerance", src: base.Add(time.Second), tolerance: tolerance, want: false},
+		{name: "newer exactly at tolerance", src: base.Add(tolerance), tolerance: tolerance, want: false},
+		{name: "newer just past tolerance", src: base.Add(tolerance + time.Nanosecond), tolerance: tolerance, want: true},
+		{name: "older exactly at tolerance", src: base.Add(-tolerance), tolerance: tolerance, want: false},
+		{name: "older just past tolerance", src: base.Add(-tolerance - time.Nanosecond), tolerance: tolerance, want: false},
+		{name: "newer without tolerance", src: base.Add(time.Nanosecond), want: true},
+		{name: "older without tolerance", src: base.Add(-time.Nanosecond), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNewer(tt.src, base, tt.tolerance); got != tt.want {
+				t.Fatalf("isNewer(%s, %s, %s) = %v, want %v", tt.src, base, tt.tolerance, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferKeepsContentEncoding(t *testing.T) {
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(bytes.Repeat([]byte("compressible text "), 1024))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	data := compressed.Bytes()
+
+	config := testConfig()
+	src := storage.NewMemoryClient(testBucket)
+	dst := storage.NewMemoryClient(testBucket)
+	store := newTestStore(t)
+	task := putSource(t, src, "page.html", data, config.PartSize, storage.PutOptions{ContentType: "text/html", ContentEncoding: "gzip"})
+
+	newTestProcessor(t, config, src, dst, store).Transfer(context.Background(), task)
+
+	assertStatus(t, store, task.Key, checkpoint.StatusCompleted)
+	got, err := dst.Data(testBucket, task.Key)
+	if err != nil {
+		t.Fatalf("read destination: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("destination holds %d bytes, want the %d gzip bytes unchanged", len(got), len(data))
+	}
+	info, err := dst.HeadObject(context.Background(), testBucket, task.Key)
+	if err != nil {
+		t.Fatalf("head destination: %v", err)
+	}
+	if info.ContentEncoding != "gzip" || info.ContentType != "text/html" {
+		t.Fatalf("destination Content-Encoding %q, Content-Type %q, want gzip, text/html", info.ContentEncoding, info.ContentType)
+	}
+}
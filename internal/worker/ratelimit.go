@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out the start of object transfers across all workers so
+// that no more than a given number of objects start per second
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time // Earliest start of the next transfer
+}
+
+// NewRateLimiter creates a limiter allowing perSecond object starts per second
+func NewRateLimiter(perSecond float64) *RateLimiter {
+	return &RateLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// Wait blocks until the next transfer may start or ctx is done. Slots are
+// handed out in order, so a burst of callers is spread over time instead of
+// starting together.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(start)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
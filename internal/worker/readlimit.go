@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"context"
+
+	"minio2rustfs/internal/metrics"
+)
+
+// ReadLimiter bounds the number of source objects being read at once across
+// all workers, independently of the worker count. A slot is held for as long
+// as a source stream is open, including reopens of a resumed stream.
+type ReadLimiter struct {
+	slots   chan struct{}
+	metrics *metrics.Collector
+}
+
+// NewReadLimiter creates a limiter allowing max concurrent source reads
+func NewReadLimiter(max int, metricsCollector *metrics.Collector) *ReadLimiter {
+	return &ReadLimiter{
+		slots:   make(chan struct{}, max),
+		metrics: metricsCollector,
+	}
+}
+
+// Acquire blocks until a read slot is free or ctx is done
+func (l *ReadLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		l.metrics.SetSourceReads(len(l.slots))
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *ReadLimiter) Release() {
+	<-l.slots
+	l.metrics.SetSourceReads(len(l.slots))
+}
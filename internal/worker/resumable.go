@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// resumableReader reads a source object and, if the stream drops before the
+// expected size has been read, reopens it with a ranged GET from the current
+// offset instead of failing the whole object. Reopened reads are pinned to the
+// listed etag so a concurrently modified object is not stitched together.
+// For byte range tasks offsets are relative to the start of the range.
+type resumableReader struct {
+	ctx     context.Context
+	client  storage.Client
+	task    Task
+	current io.ReadCloser
+	offset  int64
+	retries int // Maximum number of resumes over the whole object
+	resumes int
+	backoff func(attempt int) time.Duration
+	logger  *zap.Logger
+}
+
+func (r *resumableReader) Read(b []byte) (int, error) {
+	for {
+		n, err := r.current.Read(b)
+		r.offset += int64(n)
+
+		if err == nil || (err == io.EOF && r.offset >= r.task.Size) {
+			return n, err
+		}
+		if n > 0 {
+			// Return what was read; the error resurfaces on the next call
+			return n, nil
+		}
+		if errors.Is(err, context.Canceled) || r.ctx.Err() != nil {
+			return 0, err
+		}
+		if r.resumes >= r.retries {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, fmt.Errorf("source stream failed at offset %d after %d resumes: %w", r.offset, r.retries, err)
+		}
+
+		r.logger.Warn("Source stream interrupted, resuming with ranged GET",
+			zap.String("key", r.task.Key),
+			zap.Int64("offset", r.offset),
+			zap.Int("resume", r.resumes+1),
+			zap.Error(err),
+		)
+		r.resumes++
+		time.Sleep(r.backoff(r.resumes))
+
+		r.current.Close()
+		start, length := r.offset, int64(0)
+		if r.task.Range != nil {
+			start, length = r.task.Range.Offset+r.offset, r.task.Size-r.offset
+		}
+		obj, openErr := r.client.GetObjectRange(r.ctx, r.task.Bucket, r.task.Key, start, length, r.task.ETag)
+		if openErr != nil {
+			return 0, fmt.Errorf("failed to resume source object at offset %d: %w", r.offset, openErr)
+		}
+		r.current = obj
+	}
+}
+
+func (r *resumableReader) Close() error {
+	return r.current.Close()
+}
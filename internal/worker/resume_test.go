@@ -0,0 +1,52 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+)
+
+// TestResumeSkipsCompleted checks that a resumed run skips objects the
+// checkpoint has as completed without uploading them, even though they are
+// missing on the destination, while a fresh run ignores the checkpoint
+func TestResumeSkipsCompleted(t *testing.T) {
+	tests := []struct {
+		name         string
+		resume       bool
+		status       checkpoint.TaskStatus
+		wantUploaded bool
+	}{
+		{name: "resume completed", resume: true, status: checkpoint.StatusCompleted, wantUploaded: false},
+		{name: "resume failed", resume: true, status: checkpoint.StatusFailed, wantUploaded: true},
+		{name: "resume pending", resume: true, status: checkpoint.StatusPending, wantUploaded: true},
+		{name: "fresh run", resume: false, status: checkpoint.StatusCompleted, wantUploaded: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig()
+			config.SkipExisting = true
+			config.CompareSize = true
+			config.Resume = tt.resume
+
+			src := storage.NewMemoryClient(testBucket)
+			dst := storage.NewMemoryClient(testBucket)
+			store := newTestStore(t)
+			task := putSource(t, src, "object", testData(1024), config.PartSize, storage.PutOptions{})
+			record := &checkpoint.TaskRecord{Bucket: task.Bucket, Key: task.Key, Size: task.Size, ETag: task.ETag, Status: tt.status}
+			if err := store.SaveTask(record); err != nil {
+				t.Fatalf("save record: %v", err)
+			}
+
+			newTestProcessor(t, config, src, dst, store).Process(context.Background(), task)
+
+			_, err := dst.Data(testBucket, task.Key)
+			if uploaded := err == nil; uploaded != tt.wantUploaded {
+				t.Fatalf("uploaded %v, want %v", uploaded, tt.wantUploaded)
+			}
+			assertStatus(t, store, task.Key, checkpoint.StatusCompleted)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+)
+
+// TestReverseRoundTrip migrates objects from one side to the other and back
+// with the roles swapped, as --reverse does, and checks that nothing is lost
+func TestReverseRoundTrip(t *testing.T) {
+	config := testConfig()
+	objects := map[string]storage.PutOptions{
+		"small.txt":      {ContentType: "text/plain", Metadata: map[string]string{"owner": "team-a"}},
+		"dir/large.bin":  {ContentType: "application/octet-stream"},
+		"page.html.gz":   {ContentType: "text/html", ContentEncoding: "gzip"},
+		"unicode/日本.txt": {ContentType: "text/plain; charset=utf-8"},
+	}
+	sizes := map[string]int{"dir/large.bin": 12 << 20}
+
+	minio := storage.NewMemoryClient(testBucket)
+	var keys []string
+	for key, opts := range objects {
+		size := sizes[key]
+		if size == 0 {
+			size = 4096
+		}
+		putSource(t, minio, key, testData(size), config.PartSize, opts)
+		keys = append(keys, key)
+	}
+
+	// Forward: MinIO to RustFS, then the roles swapped into a fresh MinIO
+	// bucket with a checkpoint of its own
+	rustfs := storage.NewMemoryClient(testBucket)
+	forward := newTestStore(t)
+	p := newTestProcessor(t, config, minio, rustfs, forward)
+	for _, key := range keys {
+		p.Transfer(context.Background(), listedTask(t, minio, key))
+		assertStatus(t, forward, key, checkpoint.StatusCompleted)
+	}
+
+	restored := storage.NewMemoryClient(testBucket)
+	backward := newTestStore(t)
+	p = newTestProcessor(t, config, rustfs, restored, backward)
+	for _, key := range keys {
+		p.Transfer(context.Background(), listedTask(t, rustfs, key))
+		assertStatus(t, backward, key, checkpoint.StatusCompleted)
+	}
+
+	for _, key := range keys {
+		want, _ := minio.Data(testBucket, key)
+		got, err := restored.Data(testBucket, key)
+		if err != nil {
+			t.Fatalf("read %s: %v", key, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s: %d bytes after the round trip, want %d", key, len(got), len(want))
+		}
+
+		wantInfo, _ := minio.HeadObject(context.Background(), testBucket, key)
+		gotInfo, _ := restored.HeadObject(context.Background(), testBucket, key)
+		if gotInfo.ETag != wantInfo.ETag || gotInfo.ContentType != wantInfo.ContentType ||
+			gotInfo.ContentEncoding != wantInfo.ContentEncoding || !metadataEqual(gotInfo.Metadata, wantInfo.Metadata) {
+			t.Fatalf("%s: %+v after the round trip, want %+v", key, gotInfo, wantInfo)
+		}
+	}
+}
+
+// listedTask returns the task the lister builds for key on client
+func listedTask(t *testing.T, client storage.Client, key string) Task {
+	t.Helper()
+	info, err := client.HeadObject(context.Background(), testBucket, key)
+	if err != nil {
+		t.Fatalf("head %s: %v", key, err)
+	}
+	return Task{
+		Bucket:       testBucket,
+		Key:          key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		ContentType:  info.ContentType,
+		Metadata:     info.Metadata,
+		LastModified: info.LastModified,
+	}
+}
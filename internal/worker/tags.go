@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"minio2rustfs/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// errTagsUnsupported is reported by an upload that the destination rejected
+// because of its tags. Tag copying is off from then on, and the object is
+// transferred again without them.
+var errTagsUnsupported = errors.New("object tagging not supported")
+
+// tagCopier tracks whether object tags are copied. It is shared by all
+// workers: once either side turns out not to support object tagging, tags
+// are left out for the rest of the run, with a single warning, instead of
+// failing every object.
+type tagCopier struct {
+	unsupported atomic.Bool
+	logger      *zap.Logger
+}
+
+// newTagCopier returns nil when tags are not copied
+func newTagCopier(enabled bool, logger *zap.Logger) *tagCopier {
+	if !enabled {
+		return nil
+	}
+	return &tagCopier{logger: logger}
+}
+
+// enabled reports whether tags are still copied. A nil copier never copies.
+func (c *tagCopier) enabled() bool {
+	return c != nil && !c.unsupported.Load()
+}
+
+// disable turns tag copying off after side ("source" or "destination")
+// reported that it does not support object tagging
+func (c *tagCopier) disable(side string, err error) {
+	if c.unsupported.CompareAndSwap(false, true) {
+		c.logger.Warn("Object tagging not supported, migrating objects without tags",
+			zap.String("side", side),
+			zap.Error(err),
+		)
+	}
+}
+
+// uploadTags returns the tags to set on the destination object, or nil when
+// tags are not copied
+func (p *TaskProcessor) uploadTags(task Task) map[string]string {
+	if !p.tags.enabled() || len(task.Tags) == 0 {
+		return nil
+	}
+	return task.Tags
+}
+
+// sourceTags returns the tags of the source object to apply on the
+// destination when CopyTags is set. Listings do not include tags, so they
+// are read per object unless the task already carries them.
+func (p *TaskProcessor) sourceTags(ctx context.Context, task Task) (map[string]string, error) {
+	if !p.tags.enabled() || task.Tags != nil {
+		return task.Tags, nil
+	}
+
+	tags, err := p.srcClient.GetObjectTags(ctx, task.Bucket, task.Key)
+	if err != nil {
+		if storage.IsNotImplemented(err) {
+			p.tags.disable("source", err)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get source tags: %w", err)
+	}
+	return tags, nil
+}
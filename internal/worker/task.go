@@ -1,20 +1,119 @@
 package worker
 
+import (
+	"fmt"
+	"time"
+
+	"minio2rustfs/internal/storage"
+)
+
 // Task represents a migration task
 type Task struct {
-	Bucket      string            `json:"bucket"`
-	Key         string            `json:"key"`
-	Size        int64             `json:"size"`
-	ETag        string            `json:"etag"`
-	ContentType string            `json:"content_type"` // Add ContentType field
-	Metadata    map[string]string `json:"metadata"`
+	Bucket          string            `json:"bucket"`
+	Key             string            `json:"key"`
+	Size            int64             `json:"size"`
+	ETag            string            `json:"etag"`
+	ContentType     string            `json:"content_type"`               // Add ContentType field
+	ContentEncoding string            `json:"content_encoding,omitempty"` // Copied with the encoded bytes; never decoded
+	Metadata        map[string]string `json:"metadata"`
+	LastModified    time.Time         `json:"last_modified"`
+	DstBucket       string            `json:"dst_bucket,omitempty"` // Destination bucket when it differs from Bucket
+	DstKey          string            `json:"dst_key,omitempty"`    // Destination key when it differs from Key
+	Range           *ByteRange        `json:"range,omitempty"`      // Migrate only this part of the source object; Size is its length
+	Grants          []storage.Grant   `json:"grants,omitempty"`     // Source ACL grants applied on upload with CopyACL
+	Tags            map[string]string `json:"tags,omitempty"`       // Source object tags applied on upload with CopyTags; read per object when nil
+	Deferrals       int               `json:"deferrals,omitempty"`  // Deferred retries used so far
+	DstETag         string            `json:"dst_etag,omitempty"`   // ETag of an existing destination object that did not match, for conditional reads
+}
+
+// ByteRange is a part of a source object that is migrated as its own
+// destination object
+type ByteRange struct {
+	Offset int64 `json:"offset"`
+}
+
+// DestinationBucket returns the bucket the object is written to on the destination
+func (t Task) DestinationBucket() string {
+	if t.DstBucket != "" {
+		return t.DstBucket
+	}
+	return t.Bucket
+}
+
+// DestinationKey returns the key the object is written to on the destination
+func (t Task) DestinationKey() string {
+	if t.DstKey != "" {
+		return t.DstKey
+	}
+	return t.Key
+}
+
+// CheckpointKey returns the key the task is recorded under in the checkpoint.
+// Ranges of the same source object are recorded separately.
+func (t Task) CheckpointKey() string {
+	if t.Range != nil {
+		return fmt.Sprintf("%s#%d-%d", t.Key, t.Range.Offset, t.Range.Offset+t.Size-1)
+	}
+	return t.Key
 }
 
 // Config contains worker configuration
 type Config struct {
-	MultipartThreshold int64
-	PartSize           int64
-	Retries            int
-	RetryBackoffMs     int
-	SkipExisting       bool
+	MultipartThreshold  int64
+	MultipartMinSize    int64 // Objects below this size always use a single PUT
+	NoMultipart         bool  // Always upload with a single PUT
+	PartSize            int64
+	PartConcurrency     int               // Parts of one object uploaded at once
+	ContentTypes        map[string]string // Content-type overrides keyed by lowercased extension
+	Retries             int
+	ChecksumRetries     int  // Re-transfers after an upload checksum mismatch; 0 only warns
+	VerifyAfterPut      bool // HEAD each uploaded object to confirm its size and ETag
+	VerifyKeys          bool // List the destination to confirm each upload landed on its exact key
+	VerifyContent       bool // Read each uploaded object back and compare its SHA-256 with the source data
+	RetryBackoffMs      int
+	DeferredRetries     int // Times a task that used up its retries is transferred again after RetryCooldown
+	RetryCooldown       time.Duration
+	AutoThrottle        bool // Adjust the delay between requests based on the error ratio
+	ThrottleMaxDelay    time.Duration
+	SkipExisting        bool
+	CompareETag         bool // Attributes that must match for skip-existing to skip an object
+	CompareSize         bool
+	CompareMetadata     []string       // User metadata keys, lowercased and without the x-amz-meta- prefix
+	SyncMetadata        bool           // Update metadata of existing matching objects with a server-side copy
+	VerifyCompleted     bool           // HEAD the destination before skipping a task the checkpoint has as completed
+	CopyACL             bool           // Apply the source object's ACL grants to the destination object
+	CopyTags            bool           // Apply the source object's tags to the destination object
+	PreserveMtime       bool           // Store the source Last-Modified as original-mtime user metadata
+	Conditional         bool           // Use If-None-Match requests to skip objects the destination already holds
+	TagFailedSource     bool           // Tag source objects that failed with migration-status=failed
+	MaxTaskAttempts     int            // Runs a task may fail in before it is quarantined; 0 disables
+	SkipUnreadable      bool           // Skip objects whose source cannot be read instead of failing them
+	HeadConcurrency     int            // Goroutines running existence checks ahead of the workers; 0 checks in the workers
+	MaxSourceReads      int            // Source objects read at once across all workers; 0 is unlimited
+	MaxObjectsPerSecond float64        // Object transfers started per second across all workers; 0 is unlimited
+	MaxBandwidth        int64          // Source bytes read per second across all workers; 0 is unlimited
+	Priorities          map[string]int // Source keys migrated first, highest priority first; nil keeps listing order
+	Resume              bool           // Look up completed tasks in the checkpoint; off on fresh runs
+	RecheckSource       bool           // Re-migrate completed objects whose source size/etag changed
+	SpillDir            string         // Parts are spilled to temp files here when set
+	SpillThreshold      int64
+	StreamParts         bool // Upload parts straight from the source stream; a failed part retries the object
+	QueueDepth          int  // Tasks buffered between the existence checkers and the workers
+	PoolBuffers         bool // Reuse in-memory part buffers across parts and workers
+	CopyIfNewer         bool
+	MtimeSkewTolerance  time.Duration
+	SlowThreshold       time.Duration // Log objects taking longer than this; 0 disables
+	IdleTimeout         time.Duration // Fail an attempt when no bytes move for this long; 0 disables
+	AttemptTimeout      time.Duration // Fail an attempt that takes longer than this; 0 disables
+	ObjectTimeout       time.Duration // Give up on an object after this long across all attempts; 0 disables
+	SkipExpiringWithin  time.Duration // Skip objects the source lifecycle expires within this window; 0 disables
+	Watch               bool
+	VerboseProgress     bool  // Publish each worker's current object and offset for the progress display
+	SmallBatchSize      int   // Small objects transferred concurrently by one worker; <= 1 disables
+	SmallBatchThreshold int64 // Objects up to this size are batched
+	PackSmall           bool  // Experimental: pack objects below PackThreshold into tar archives
+	PackThreshold       int64
+	PackMaxSize         int64
+	PackPrefix          string
+	OnFailure           func(task Task, err error) // Called for each task that failed after all retries; may be nil
 }
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"minio2rustfs/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// throttleWindow is the number of request outcomes per control step
+	throttleWindow = 20
+	// throttleErrorRatio is the error ratio above which requests are slowed down
+	throttleErrorRatio = 0.1
+	// throttleStep is the additive decrease of the delay on a healthy window,
+	// and the initial delay when throttling kicks in
+	throttleStep = 10 * time.Millisecond
+)
+
+// Throttle is an AIMD controller for the delay between requests shared by all
+// workers. A window with too many errors doubles the delay (multiplicative
+// decrease of the request rate); a healthy window shrinks it by throttleStep
+// (additive increase) until requests are no longer delayed.
+type Throttle struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	maxDelay  time.Duration
+	next      time.Time // Earliest start of the next request
+	successes int
+	failures  int
+	metrics   *metrics.Collector
+	logger    *zap.Logger
+}
+
+// NewThrottle creates a throttle whose delay never exceeds maxDelay
+func NewThrottle(maxDelay time.Duration, metricsCollector *metrics.Collector, logger *zap.Logger) *Throttle {
+	return &Throttle{
+		maxDelay: maxDelay,
+		metrics:  metricsCollector,
+		logger:   logger,
+	}
+}
+
+// Wait blocks until the next request slot. Slots are spaced by the current
+// delay across all workers, so the delay bounds the aggregate request rate.
+func (t *Throttle) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.delay == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	slot := t.next
+	if slot.Before(now) {
+		slot = now
+	}
+	t.next = slot.Add(t.delay)
+	t.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Record feeds the outcome of a request into the controller
+func (t *Throttle) Record(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err == nil {
+		t.successes++
+	} else {
+		t.failures++
+	}
+
+	total := t.successes + t.failures
+	if total < throttleWindow {
+		return
+	}
+
+	ratio := float64(t.failures) / float64(total)
+	t.successes, t.failures = 0, 0
+
+	previous := t.delay
+	if ratio > throttleErrorRatio {
+		t.delay = t.delay * 2
+		if t.delay < throttleStep {
+			t.delay = throttleStep
+		}
+		if t.delay > t.maxDelay {
+			t.delay = t.maxDelay
+		}
+	} else {
+		t.delay -= throttleStep
+		if t.delay < 0 {
+			t.delay = 0
+		}
+	}
+
+	if t.delay == previous {
+		return
+	}
+
+	t.metrics.SetThrottleDelay(t.delay)
+	t.logger.Info("Adjusted request throttle",
+		zap.Float64("error_ratio", ratio),
+		zap.Duration("delay", t.delay),
+		zap.Duration("previous_delay", previous),
+	)
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"minio2rustfs/internal/checkpoint"
+	"minio2rustfs/internal/storage"
+)
+
+// decodingClient stores objects under the key a server that form-decodes
+// paths would use, turning "+" into a space
+type decodingClient struct {
+	*storage.MemoryClient
+}
+
+func (c decodingClient) PutObject(ctx context.Context, bucket, key string, reader io.Reader, size int64, opts storage.PutOptions) (string, error) {
+	return c.MemoryClient.PutObject(ctx, bucket, strings.ReplaceAll(key, "+", " "), reader, size, opts)
+}
+
+func TestVerifyKeys(t *testing.T) {
+	keys := []string{
+		"with space.txt",
+		"a+b.txt",
+		"unicode/日本語/ünïcödé.txt",
+		"percent/100%.txt",
+		"percent/escaped%2Fslash.txt",
+		"trailing/slash/",
+	}
+
+	config := testConfig()
+	config.VerifyKeys = true
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			src := storage.NewMemoryClient(testBucket)
+			dst := storage.NewMemoryClient(testBucket)
+			store := newTestStore(t)
+			task := putSource(t, src, key, testData(1024), config.PartSize, storage.PutOptions{})
+
+			newTestProcessor(t, config, src, dst, store).Transfer(context.Background(), task)
+
+			assertStatus(t, store, key, checkpoint.StatusCompleted)
+			var listed []string
+			objCh, errCh := dst.ListObjects(context.Background(), testBucket, "", storage.ListOptions{})
+			for obj := range objCh {
+				listed = append(listed, obj.Key)
+			}
+			if err := <-errCh; err != nil {
+				t.Fatalf("list destination: %v", err)
+			}
+			if len(listed) != 1 || listed[0] != key {
+				t.Fatalf("destination keys %q, want exactly %q", listed, key)
+			}
+		})
+	}
+}
+
+func TestVerifyKeysMismatch(t *testing.T) {
+	config := testConfig()
+	config.VerifyKeys = true
+	src := storage.NewMemoryClient(testBucket)
+	dst := storage.NewMemoryClient(testBucket)
+	store := newTestStore(t)
+	task := putSource(t, src, "a+b.txt", testData(1024), config.PartSize, storage.PutOptions{})
+
+	newTestProcessor(t, config, src, decodingClient{dst}, store).Transfer(context.Background(), task)
+
+	record := assertStatus(t, store, task.Key, checkpoint.StatusFailed)
+	if !strings.Contains(record.LastError, ErrKeyMismatch.Error()) {
+		t.Fatalf("last error %q, want a key mismatch", record.LastError)
+	}
+	if _, err := dst.Data(testBucket, "a b.txt"); err != nil {
+		t.Fatalf("decoded key not written: %v", err)
+	}
+}